@@ -0,0 +1,39 @@
+package api
+
+import (
+	"math/rand"
+	"time"
+)
+
+// defaultPullInterval 未指定--pull-interval时拉取任务的初始等待时间
+const defaultPullInterval = 5 * time.Second
+
+// backoff 指数退避，每次等待时间翻倍直到max，实际等待时间在[d/2, d]之间随机，避免大量worker同时请求
+type backoff struct {
+	min  time.Duration
+	max  time.Duration
+	next time.Duration
+}
+
+func newBackoff(minWait time.Duration, maxWait time.Duration) *backoff {
+	if minWait <= 0 {
+		minWait = defaultPullInterval
+	}
+	if maxWait < minWait {
+		maxWait = minWait
+	}
+	return &backoff{min: minWait, max: maxWait, next: minWait}
+}
+
+// duration 获取本次需要等待的时间，并将下次等待时间翻倍
+func (b *backoff) duration() time.Duration {
+	d := b.next
+	b.next = min(b.next*2, b.max)
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// reset 重置为初始等待时间
+func (b *backoff) reset() {
+	b.next = b.min
+}
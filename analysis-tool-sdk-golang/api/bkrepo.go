@@ -1,7 +1,6 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -13,20 +12,39 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // analystTemporaryPrefix 制品分析服务接口前缀
 const analystTemporaryPrefix = "/api/analyst/api/temporary"
 
+// longPollToleranceRatio 拉取请求耗时达到--pull-wait的该比例时认为服务端长轮询等待过
+const longPollToleranceRatio = 0.9
+
 var client *BkRepoClient
 
+// globalClientRejections 大于0时同时执行多个任务，不能通过GetClient获取任务
+var globalClientRejections atomic.Int32
+
 // BkRepoClient 为分析任务的输入输出操作提供同一入口
 type BkRepoClient struct {
 	Args      *object.Arguments
 	ToolInput *object.ToolInput
+	// WorkDir 当前客户端使用的工作空间根目录，每个任务使用其中以任务id命名的子目录
+	WorkDir string
+	// Outbox 保存上报失败的结果，为nil时不保存
+	Outbox *Outbox
+	// Reporter 输出或上报分析结果
+	Reporter Reporter
+	// Progress 当前任务的执行进度，通过心跳上报
+	Progress *util.ProgressTracker
+	// lastPull 最近一次成功请求制品分析服务拉取任务的时间，单位为毫秒
+	lastPull atomic.Int64
 }
 
 // Response 制品分析服务响应
@@ -52,28 +70,73 @@ type ReportResultRequest struct {
 }
 
 // GetClient 获取BkRepoClient
+// 同时执行多个任务时全局客户端中的ToolInput不是当前任务，此时会panic，应通过framework.TaskFromContext获取任务
 func GetClient(args *object.Arguments) *BkRepoClient {
+	if globalClientRejections.Load() > 0 {
+		panic("同时执行多个任务时不能通过api.GetClient获取任务，请使用framework.TaskFromContext或将--parallel设置为1")
+	}
 	if client == nil {
-		client = &BkRepoClient{args, nil}
+		client = NewClient(args, WorkRoot(args))
 	}
 	return client
 }
 
+// RejectGlobalClient 禁止通过GetClient获取全局客户端，直到调用返回的restore，用于同时执行多个任务时
+func RejectGlobalClient() (restore func()) {
+	globalClientRejections.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { globalClientRejections.Add(-1) })
+	}
+}
+
+// WorkRoot 获取工作空间根目录，未通过--work-dir指定时使用util.WorkDir
+func WorkRoot(args *object.Arguments) string {
+	if args.WorkDir == "" {
+		return util.WorkDir
+	}
+	return args.WorkDir
+}
+
+// NewClient 创建BkRepoClient，并发执行任务时每个worker需要使用独立的客户端
+func NewClient(args *object.Arguments, workDir string) *BkRepoClient {
+	outbox := NewOutbox(args.OutboxDir)
+	return &BkRepoClient{Args: args, WorkDir: workDir, Outbox: outbox, Reporter: NewReporter(args, outbox)}
+}
+
 // Start 开始分析
 func (c *BkRepoClient) Start(ctx context.Context, cancel context.CancelFunc) (*object.ToolInput, error) {
+	return c.StartWithPullContext(ctx, ctx, cancel)
+}
+
+// StartWithPullContext 开始分析，pullCtx结束时停止拉取任务，已拉取到的任务不受影响，ctx结束时停止心跳
+func (c *BkRepoClient) StartWithPullContext(
+	ctx context.Context,
+	pullCtx context.Context,
+	cancel context.CancelFunc,
+) (*object.ToolInput, error) {
 	if c.ToolInput == nil {
-		if err := c.initToolInput(); err != nil {
+		if err := c.initToolInput(pullCtx); err != nil {
 			return nil, err
 		}
+		if c.ToolInput == nil || c.ToolInput.TaskId == "" {
+			// 未拉取到任务
+			c.ToolInput = nil
+			return nil, nil
+		}
 		util.Info("init tool input success: %s", c.ToolInput.TaskId)
+		util.MetricTasksPulled.Inc()
+		c.Progress = util.NewProgressTracker()
 
 		// 是在线任务时，更新任务状态为执行中
-		if c.Args.Online() {
+		if c.Args.Online() && !c.Args.LocalInput() {
 			if err := c.updateSubtaskStatus(); err != nil {
+				// 未开始执行，下次调用时重新拉取任务
+				c.ToolInput = nil
 				return nil, err
 			}
 			if c.Args.Heartbeat > 0 {
-				go c.heartbeat(ctx, cancel)
+				go c.heartbeat(ctx, cancel, c.Progress)
 			}
 			util.Info("update subtask status success")
 		}
@@ -81,58 +144,93 @@ func (c *BkRepoClient) Start(ctx context.Context, cancel context.CancelFunc) (*o
 	return c.ToolInput, nil
 }
 
-// Finish 分析结束
-func (c *BkRepoClient) Finish(cancel context.CancelFunc, toolOutput *object.ToolOutput) {
-	toolOutput.TaskId = c.ToolInput.TaskId
+// Finish 分析结束，使用Reporter输出或上报结果，失败时返回的error中包含*ReportError
+func (c *BkRepoClient) Finish(cancel context.CancelFunc, toolOutput *object.ToolOutput) error {
 	cancel()
-	if c.Args.Offline() {
-		if err := util.WriteToFile(c.Args.OutputFilePath, toolOutput); err != nil {
-			panic("Finish analyze failed: " + err.Error())
-		}
+	if c.ToolInput == nil {
+		return ErrNotStarted
+	}
+	defer func() { c.ToolInput = nil }()
+	toolOutput.TaskId = c.ToolInput.TaskId
+	start := time.Now()
+	err := c.Reporter.Report(context.Background(), toolOutput)
+	if err != nil {
+		util.MetricReportDuration.ObserveSince(start, "failed")
 	} else {
-		reqUrl := c.Args.Url + analystTemporaryPrefix + "/scan/report"
-		result := StandardScanExecutorResult{"standard", toolOutput.Status, toolOutput}
-		reqBody, err := json.Marshal(
-			ReportResultRequest{
-				SubTaskId:          c.ToolInput.TaskId,
-				ScanStatus:         toolOutput.Status,
-				ScanExecutorResult: &result,
-				Token:              c.Args.Token,
-			},
-		)
-		if err != nil {
-			panic("Finish analyze failed: " + err.Error())
-		}
-		req, err := retryablehttp.NewRequest("POST", reqUrl, bytes.NewReader(reqBody))
-		if err != nil {
-			panic("Finish analyze failed: " + err.Error())
-		}
-		req.Header.Add("Content-Type", "application/json; charset=UTF-8")
-		res, err := util.DefaultClient.Do(req)
-		if err != nil {
-			panic("Report analysis result failed, taskId: " + toolOutput.TaskId + ", err: " + err.Error())
-		}
-		defer util.DrainBody(res.Body)
-		if res.StatusCode != 200 {
-			panic("Report analysis result failed, taskId: " + toolOutput.TaskId)
-		}
+		util.MetricReportDuration.ObserveSince(start, "success")
 	}
-	c.ToolInput = nil
+	var reportErr *ReportError
+	if err != nil && !errors.As(err, &reportErr) {
+		return &ReportError{TaskId: toolOutput.TaskId, Err: err}
+	}
+	return err
 }
 
-func (c *BkRepoClient) Failed(cancel context.CancelFunc, err error) {
+// RedeliverReports 重新上报之前上报失败的结果
+func (c *BkRepoClient) RedeliverReports() {
+	if c.Outbox == nil || !c.Args.Online() {
+		return
+	}
+	reporter := &AnalystReporter{Args: c.Args, Outbox: c.Outbox}
+	reporter.Redeliver(context.Background())
+}
+
+// Failed 分析失败
+func (c *BkRepoClient) Failed(cancel context.CancelFunc, err error) error {
 	util.Error("analyze failed %s", err)
 	output := object.NewFailedOutput(err)
-	c.Finish(cancel, output)
+	return c.Finish(cancel, output)
+}
+
+// Stopped 分析被中止
+func (c *BkRepoClient) Stopped(cancel context.CancelFunc) error {
+	if c.ToolInput != nil {
+		util.Warn("analyze stopped, taskId: %s", c.ToolInput.TaskId)
+	}
+	output := object.NewErrorOutput(errors.New("analysis stopped"), object.StatusStopped)
+	return c.Finish(cancel, output)
+}
+
+// LastPull 最近一次成功请求制品分析服务拉取任务的时间，未成功拉取过时返回零值
+func (c *BkRepoClient) LastPull() time.Time {
+	if ms := c.lastPull.Load(); ms > 0 {
+		return time.UnixMilli(ms)
+	}
+	return time.Time{}
+}
+
+// TaskWorkDir 当前任务的工作空间，未开始任务时返回工作空间根目录
+func (c *BkRepoClient) TaskWorkDir() string {
+	if c.ToolInput == nil || c.ToolInput.TaskId == "" {
+		return c.WorkDir
+	}
+	return filepath.Join(c.WorkDir, safeFileName(c.ToolInput.TaskId))
+}
+
+// safeFileName 将任务id转换为文件名，避免任务id中包含路径分隔符时写到指定目录之外
+func safeFileName(id string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(id)
+	if name == "." || name == ".." {
+		name = "_"
+	}
+	return name
 }
 
 // GenerateInputFile 生成待分析文件
 func (c *BkRepoClient) GenerateInputFile() (*os.File, error) {
+	return c.GenerateInputFileContext(context.Background())
+}
+
+// GenerateInputFileContext 生成待分析文件，并将下载进度更新到当前任务的进度中
+func (c *BkRepoClient) GenerateInputFileContext(ctx context.Context) (*os.File, error) {
 	downloader, err := c.createDownloader()
 	if err != nil {
 		return nil, err
 	}
-	return util.GenerateInputFile(c.ToolInput, downloader)
+	if c.Progress != nil {
+		ctx = util.WithProgress(ctx, c.Progress)
+	}
+	return util.GenerateInputFileContext(ctx, c.ToolInput, downloader, c.TaskWorkDir())
 }
 
 func (c *BkRepoClient) createDownloader() (util.Downloader, error) {
@@ -140,20 +238,12 @@ func (c *BkRepoClient) createDownloader() (util.Downloader, error) {
 	workerCount, _ := c.ToolInput.ToolConfig.GetIntArg(util.ArgKeyDownloaderWorkerCount)
 	if workerCount > 0 {
 		// 解析header
-		downloaderHeadersStr := c.ToolInput.ToolConfig.GetStringArg(util.ArgKeyDownloaderWorkerHeaders)
-		headers := make(map[string]string)
-		if len(downloaderHeadersStr) > 0 {
-			downloaderHeaders := strings.Split(downloaderHeadersStr, ",")
-			for i := range downloaderHeaders {
-				h := strings.Split(downloaderHeaders[i], ":")
-				if len(h) != 2 {
-					return nil, errors.New("headers error: " + downloaderHeaders[i])
-				}
-				headers[strings.TrimSpace(h[0])] = strings.TrimSpace(h[1])
-			}
+		headers, err := c.ToolInput.ToolConfig.GetMapArg(util.ArgKeyDownloaderWorkerHeaders)
+		if err != nil {
+			return nil, err
 		}
 		// 创建下载器并生成待分析文件
-		downloader = util.NewChunkDownloader(int(workerCount), util.WorkDir, headers)
+		downloader = util.NewChunkDownloader(int(workerCount), c.TaskWorkDir(), headers)
 	} else {
 		downloader = util.NewDownloader()
 	}
@@ -172,8 +262,9 @@ func (c *BkRepoClient) updateSubtaskStatus() error {
 		return err
 	}
 	defer util.DrainBody(response.Body)
-	if response.StatusCode != 200 {
-		return errors.New("更新扫描任务[" + c.ToolInput.TaskId + "]状态失败, status: " + response.Status)
+	if response.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("更新扫描任务[%s]状态失败: %w", c.ToolInput.TaskId, newStatusError(response.StatusCode, errBody))
 	}
 
 	res := new(Response[bool])
@@ -188,13 +279,11 @@ func (c *BkRepoClient) updateSubtaskStatus() error {
 	return nil
 }
 
-func (c *BkRepoClient) heartbeat(ctx context.Context, cancel context.CancelFunc) {
+// heartbeat 定时发送心跳，心跳请求中包含progress记录的最新进度
+func (c *BkRepoClient) heartbeat(ctx context.Context, cancel context.CancelFunc, progress *util.ProgressTracker) {
 	ticker := time.NewTicker(time.Duration(c.Args.Heartbeat) * time.Second)
 	taskId := c.ToolInput.TaskId
 	reqUrl := c.Args.Url + analystTemporaryPrefix + "/scan/subtask/" + taskId + "/heartbeat"
-	data := url.Values{}
-	data.Set("token", c.Args.Token)
-	body := data.Encode()
 	for {
 		select {
 		case <-ctx.Done():
@@ -202,17 +291,21 @@ func (c *BkRepoClient) heartbeat(ctx context.Context, cancel context.CancelFunc)
 			ticker.Stop()
 			return
 		case <-ticker.C:
-			request, err := retryablehttp.NewRequest(http.MethodPost, reqUrl, strings.NewReader(body))
+			request, err := retryablehttp.NewRequest(http.MethodPost, reqUrl, strings.NewReader(c.heartbeatBody(progress)))
 			if err != nil {
+				util.MetricHeartbeatFailures.Inc()
 				util.Error("heartbeat failed: " + err.Error())
+				continue
 			}
 			request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 			response, err := util.DefaultClient.Do(request)
 			if err != nil {
+				util.MetricHeartbeatFailures.Inc()
 				util.Error("heartbeat failed: " + err.Error())
 				return
 			}
 			if response.StatusCode != http.StatusOK {
+				util.MetricHeartbeatFailures.Inc()
 				cancel()
 				b, _ := io.ReadAll(response.Body)
 				util.Error("heartbeat failed: " + response.Status + ", message: " + string(b))
@@ -222,8 +315,20 @@ func (c *BkRepoClient) heartbeat(ctx context.Context, cancel context.CancelFunc)
 	}
 }
 
+// heartbeatBody 心跳请求体，有进度时通过progress字段携带json格式的进度
+func (c *BkRepoClient) heartbeatBody(progress *util.ProgressTracker) string {
+	data := url.Values{}
+	data.Set("token", c.Args.Token)
+	if p := progress.Get(); p != nil {
+		if b, err := json.Marshal(p); err == nil {
+			data.Set("progress", string(b))
+		}
+	}
+	return data.Encode()
+}
+
 // initToolInput 从本地加载input.json或从服务端拉取toolInput信息
-func (c *BkRepoClient) initToolInput() error {
+func (c *BkRepoClient) initToolInput(ctx context.Context) error {
 	if c.Args.Offline() {
 		fileContent, err := os.ReadFile(c.Args.InputFilePath)
 		if err != nil {
@@ -234,14 +339,19 @@ func (c *BkRepoClient) initToolInput() error {
 			return err
 		}
 		c.ToolInput = toolInput
+	} else if c.Args.ScanFile() {
+		var err error
+		if c.ToolInput, err = c.Args.NewFileToolInput(); err != nil {
+			return err
+		}
 	} else if c.Args.TaskId != "" {
 		var err error
-		if c.ToolInput, err = c.fetchToolInput(c.Args.TaskId); err != nil {
+		if c.ToolInput, err = c.fetchToolInput(ctx, c.Args.TaskId); err != nil {
 			return err
 		}
 	} else {
 		var err error
-		if c.ToolInput, err = c.pullToolInput(); err != nil {
+		if c.ToolInput, err = c.pullToolInput(ctx); err != nil {
 			return err
 		}
 	}
@@ -249,46 +359,91 @@ func (c *BkRepoClient) initToolInput() error {
 }
 
 // fetchToolInput 从制品分析服务拉取工具输入
-func (c *BkRepoClient) fetchToolInput(taskId string) (*object.ToolInput, error) {
+func (c *BkRepoClient) fetchToolInput(ctx context.Context, taskId string) (*object.ToolInput, error) {
 	reqUrl := c.Args.Url + analystTemporaryPrefix + "/scan/subtask/" + taskId + "/input?token=" + c.Args.Token
-	return c.doFetchToolInput(reqUrl)
+	return c.doFetchToolInput(ctx, reqUrl)
 }
 
-// pullTooInput 从制品分析服务拉取工具输入
-func (c *BkRepoClient) pullToolInput() (*object.ToolInput, error) {
+// pullTooInput 从制品分析服务拉取工具输入，ctx结束时停止拉取并返回nil
+// 未拉取到任务时按指数退避等待后重新拉取，服务端支持长轮询时立即重新拉取，连续出错超过--pull-max-errors次时返回错误
+func (c *BkRepoClient) pullToolInput(ctx context.Context) (*object.ToolInput, error) {
 	reqUrl := c.Args.Url + analystTemporaryPrefix + "/scan/subtask/input?executionCluster=" + c.Args.ExecutionCluster +
 		"&token=" + c.Args.Token
+	if c.Args.PullWait > 0 {
+		reqUrl += "&wait=" + strconv.FormatInt(int64(c.Args.PullWait/time.Second), 10)
+	}
 
-	var toolInput *object.ToolInput = nil
-	var err error = nil
-	var pullRetry = c.Args.PullRetry
-	for (toolInput == nil || toolInput.TaskId == "") && pullRetry != 0 {
-		if err != nil {
-			return nil, err
+	emptyBackoff := newBackoff(c.Args.PullInterval, c.Args.PullMaxInterval)
+	errorBackoff := newBackoff(c.Args.PullInterval, c.Args.PullMaxInterval)
+	errorCount := 0
+	pullRetry := c.Args.PullRetry
+	for pullRetry != 0 {
+		if ctx.Err() != nil {
+			util.Info("stop pulling subtask")
+			return nil, nil
 		}
 		util.Info("try to pull subtask...")
-		toolInput, err = c.doFetchToolInput(reqUrl)
+		start := time.Now()
+		toolInput, err := c.doFetchToolInput(ctx, reqUrl)
+		held := c.longPollHeld(time.Since(start))
 		pullRetry--
-		if toolInput == nil || toolInput.TaskId == "" {
-			time.Sleep(5 * time.Second)
+
+		var wait time.Duration
+		switch {
+		case err != nil && ctx.Err() != nil:
+			util.Info("stop pulling subtask")
+			return nil, nil
+		case err != nil:
+			errorCount++
+			if pullRetry == 0 || c.Args.PullMaxErrors >= 0 && errorCount > c.Args.PullMaxErrors {
+				return nil, err
+			}
+			wait = errorBackoff.duration()
+			util.Warn("pull subtask failed %d times: %s, retry after %s", errorCount, err.Error(), wait)
+		case toolInput != nil && toolInput.TaskId != "":
+			c.lastPull.Store(time.Now().UnixMilli())
+			return toolInput, nil
+		default:
+			c.lastPull.Store(time.Now().UnixMilli())
+			util.MetricEmptyPulls.Inc()
+			errorCount = 0
+			errorBackoff.reset()
+			if held {
+				// 请求耗时接近--pull-wait说明服务端已经等待过，立即重新拉取
+				emptyBackoff.reset()
+				continue
+			}
+			wait = emptyBackoff.duration()
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
 		}
 	}
+	return nil, nil
+}
 
-	return toolInput, err
+// longPollHeld 根据拉取请求耗时判断服务端是否长轮询等待过，未指定--pull-wait时始终为false
+func (c *BkRepoClient) longPollHeld(elapsed time.Duration) bool {
+	// 请求参数中的wait精确到秒
+	wait := c.Args.PullWait.Truncate(time.Second)
+	return wait > 0 && elapsed >= time.Duration(float64(wait)*longPollToleranceRatio)
 }
 
-func (c *BkRepoClient) doFetchToolInput(url string) (*object.ToolInput, error) {
-	response, err := util.DefaultClient.Get(url)
+// doFetchToolInput 请求工具输入
+func (c *BkRepoClient) doFetchToolInput(ctx context.Context, url string) (*object.ToolInput, error) {
+	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := util.DefaultClient.Do(request)
 	if err != nil {
 		return nil, err
 	}
 	defer util.DrainBody(response.Body)
 	if response.StatusCode != http.StatusOK {
-		errBody, _ := io.ReadAll(response.Body)
-		errMsg := fmt.Sprintf(
-			"get tool input failed, status: %d, error body: %s", http.StatusOK, string(errBody),
-		)
-		return nil, errors.New(errMsg)
+		errBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("get tool input failed: %w", newStatusError(response.StatusCode, errBody))
 	}
 
 	res := new(Response[object.ToolInput])
@@ -2,18 +2,24 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
 	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestPullTask(t *testing.T) {
 	client := createClient()
-	toolInput, err := client.pullToolInput()
+	toolInput, err := client.pullToolInput(context.Background())
 	if err != nil {
 		fmt.Println(err.Error())
 	}
@@ -58,7 +64,7 @@ func TestCreateDownloader(t *testing.T) {
 		_ = reader.Close()
 	}
 
-	_ = os.RemoveAll(util.WorkDir)
+	_ = os.RemoveAll(client.WorkDir)
 }
 
 func TestHeartbeat(t *testing.T) {
@@ -67,19 +73,176 @@ func TestHeartbeat(t *testing.T) {
 	client.ToolInput = &object.ToolInput{}
 	client.ToolInput.TaskId = os.Getenv("TASK_ID")
 	ctx, cancel := context.WithCancel(context.Background())
-	go client.heartbeat(ctx)
+	go client.heartbeat(ctx, cancel, util.NewProgressTracker())
 	time.Sleep(10 * time.Second)
 	cancel()
 	time.Sleep(5 * time.Second)
 }
 
 func createClient() *BkRepoClient {
-	client := BkRepoClient{}
-	client.Args = &object.Arguments{
+	args := &object.Arguments{
 		Url:              os.Getenv("URL"),
 		Token:            os.Getenv("TOKEN"),
 		ExecutionCluster: os.Getenv("EXECUTION_CLUSTER"),
 		PullRetry:        -1,
 	}
-	return &client
+	return NewClient(args, filepath.Join(os.TempDir(), "bkrepo-analysis-workspace"))
+}
+
+func TestHeartbeatBody(t *testing.T) {
+	client := createClient()
+	client.Args.Token = "token"
+	progress := util.NewProgressTracker()
+	if body := client.heartbeatBody(progress); body != "token=token" {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	progress.Update(context.Background(), util.ProgressStageScanning, 50, map[string]int64{"files": 3})
+	values, err := url.ParseQuery(client.heartbeatBody(progress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"stage":"scanning","percent":50,"counters":{"files":3}}`
+	if values.Get("progress") != expected {
+		t.Errorf("expected %s, got %s", expected, values.Get("progress"))
+	}
+}
+
+func TestPullToolInputErrorBudget(t *testing.T) {
+	var count atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 400不会被retryablehttp重试
+		if count.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Response[object.ToolInput]{Data: object.ToolInput{TaskId: "task-1"}})
+	}))
+	defer server.Close()
+	client := NewClient(&object.Arguments{
+		Url:              server.URL,
+		ExecutionCluster: "test",
+		PullRetry:        -1,
+		PullInterval:     10 * time.Millisecond,
+		PullMaxErrors:    2,
+	}, t.TempDir())
+
+	toolInput, err := client.pullToolInput(context.Background())
+	if err != nil || toolInput.TaskId != "task-1" {
+		t.Fatalf("expect task-1, got %v, %v", toolInput, err)
+	}
+
+	count.Store(-10)
+	if _, err := client.pullToolInput(context.Background()); err == nil {
+		t.Fatal("expect error after error budget exhausted")
+	}
+	if count.Load() != -7 {
+		t.Errorf("expect 3 requests, got %d", count.Load()+10)
+	}
+}
+
+func TestPullToolInputLongPoll(t *testing.T) {
+	var count atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := Response[object.ToolInput]{}
+		if count.Add(1) < 3 {
+			if r.URL.Query().Get("wait") != "1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			// 模拟服务端长轮询等待后返回空结果
+			time.Sleep(time.Second)
+		} else {
+			res.Data.TaskId = "task-1"
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+	client := NewClient(&object.Arguments{
+		Url:              server.URL,
+		ExecutionCluster: "test",
+		PullRetry:        -1,
+		PullInterval:     time.Minute,
+		PullWait:         time.Second,
+	}, t.TempDir())
+
+	// 服务端长轮询时不需要等待--pull-interval
+	start := time.Now()
+	toolInput, err := client.pullToolInput(context.Background())
+	if err != nil || toolInput.TaskId != "task-1" {
+		t.Fatalf("expect task-1, got %v, %v", toolInput, err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("long poll should not backoff, took %s", time.Since(start))
+	}
+}
+
+func TestPullToolInputSlowWithoutLongPoll(t *testing.T) {
+	var count atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := Response[object.ToolInput]{}
+		if count.Add(1) < 2 {
+			// 响应慢但服务端未长轮询
+			time.Sleep(600 * time.Millisecond)
+		} else {
+			res.Data.TaskId = "task-1"
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+	client := NewClient(&object.Arguments{
+		Url:              server.URL,
+		ExecutionCluster: "test",
+		PullRetry:        -1,
+		PullInterval:     2 * time.Second,
+		PullWait:         time.Second,
+	}, t.TempDir())
+
+	start := time.Now()
+	toolInput, err := client.pullToolInput(context.Background())
+	if err != nil || toolInput.TaskId != "task-1" {
+		t.Fatalf("expect task-1, got %v, %v", toolInput, err)
+	}
+	if elapsed := time.Since(start); elapsed < 1600*time.Millisecond {
+		t.Errorf("expect backoff when server did not hold the request, took %s", elapsed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(time.Second, 3*time.Second)
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if d := b.duration(); d < expected/2 || d > expected {
+			t.Errorf("backoff %d: %s not in [%s, %s]", i, d, expected/2, expected)
+		}
+	}
+	b.reset()
+	if d := b.duration(); d > time.Second {
+		t.Errorf("expect reset, got %s", d)
+	}
+}
+
+func TestStartUpdateStatusFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Response[object.ToolInput]{Data: object.ToolInput{TaskId: "task-1"}})
+	}))
+	defer server.Close()
+	client := NewClient(&object.Arguments{
+		Url:              server.URL,
+		Token:            "token",
+		ExecutionCluster: "test",
+		PullRetry:        -1,
+	}, t.TempDir())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := client.StartWithPullContext(ctx, ctx, cancel); err == nil {
+		t.Fatal("expect error when update subtask status failed")
+	}
+	if client.ToolInput != nil {
+		t.Errorf("expect tool input reset, got %s", client.ToolInput.TaskId)
+	}
 }
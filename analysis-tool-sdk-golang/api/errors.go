@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotStarted 当前客户端没有正在执行的任务
+var ErrNotStarted = errors.New("analysis not started")
+
+// StatusError 制品分析服务返回了非预期的响应
+type StatusError struct {
+	// StatusCode HTTP响应码
+	StatusCode int
+	// Body 响应内容
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// ReportError 分析结果输出或上报失败
+type ReportError struct {
+	// TaskId 上报失败的任务
+	TaskId string
+	// Err 失败原因
+	Err error
+	// Saved 结果是否已保存到Outbox等待重新上报
+	Saved bool
+}
+
+func (e *ReportError) Error() string {
+	msg := fmt.Sprintf("report analysis result failed, taskId: %s, err: %s", e.TaskId, e.Err)
+	if e.Saved {
+		msg += ", result saved to outbox"
+	}
+	return msg
+}
+
+func (e *ReportError) Unwrap() error {
+	return e.Err
+}
+
+// newStatusError 读取响应内容并创建StatusError
+func newStatusError(statusCode int, body []byte) *StatusError {
+	return &StatusError{StatusCode: statusCode, Body: string(body)}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// outboxFileExt 待重新上报的结果文件后缀
+const outboxFileExt = ".json"
+
+// malformedFileExt 无法解析的结果文件重命名后的后缀，不会再重新上报
+const malformedFileExt = ".malformed"
+
+// errMalformedReport 结果文件无法解析，重试也不会成功
+var errMalformedReport = errors.New("malformed report")
+
+var (
+	// outboxLock 同一进程中的多个worker共用一个outbox目录，需要串行读写文件，重新上报时不持有锁
+	outboxLock sync.Mutex
+	// outboxSending 正在重新上报的结果文件，值表示上报期间文件是否被新的结果覆盖
+	outboxSending = make(map[string]bool)
+)
+
+// Outbox 持久化上报失败的分析结果，在下次拉取任务前或进程重启后重新上报
+// 每个子任务只保留最后一次上报失败的结果
+type Outbox struct {
+	Dir string
+}
+
+// NewOutbox 创建Outbox，dir为空时返回nil表示不保存上报失败的结果
+func NewOutbox(dir string) *Outbox {
+	if dir == "" {
+		return nil
+	}
+	return &Outbox{Dir: dir}
+}
+
+// Save 保存上报失败的结果，已存在相同子任务的结果时会被覆盖
+// 令牌不会保存到文件中，重新上报时使用当前的令牌
+func (o *Outbox) Save(req *ReportResultRequest) error {
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+	if err := os.MkdirAll(o.Dir, 0700); err != nil {
+		return err
+	}
+	saved := *req
+	saved.Token = ""
+	content, err := json.Marshal(&saved)
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免进程退出时留下不完整的文件
+	tmp, err := os.CreateTemp(o.Dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	p := o.path(req.SubTaskId)
+	if _, ok := outboxSending[p]; ok {
+		outboxSending[p] = true
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Redeliver 重新上报所有保存的结果，上报成功或被服务端拒绝的结果会被删除，无法解析的结果会被重命名，返回上报成功的数量
+func (o *Outbox) Redeliver(send func(req *ReportResultRequest) error) (int, error) {
+	outboxLock.Lock()
+	entries, err := os.ReadDir(o.Dir)
+	outboxLock.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), outboxFileExt) {
+			continue
+		}
+		p := filepath.Join(o.Dir, entry.Name())
+		req, claimed, sendErr := o.claim(p)
+		if !claimed {
+			continue
+		}
+		if errors.Is(sendErr, errMalformedReport) {
+			errs = append(errs, o.setAside(p, sendErr))
+			continue
+		}
+		if sendErr == nil {
+			sendErr = send(req)
+		}
+		if sendErr != nil && !isRejected(sendErr) {
+			o.release(p, false)
+			errs = append(errs, sendErr)
+			continue
+		}
+		if sendErr != nil {
+			util.Warn("drop rejected report %s: %s", entry.Name(), sendErr.Error())
+		}
+		if err := o.release(p, true); err != nil {
+			errs = append(errs, err)
+		} else if sendErr == nil {
+			delivered++
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// claim 标记结果文件正在重新上报并读取内容，文件已被其他worker处理时claimed为false
+func (o *Outbox) claim(p string) (req *ReportResultRequest, claimed bool, err error) {
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+	if _, ok := outboxSending[p]; ok {
+		return nil, false, nil
+	}
+	if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	outboxSending[p] = false
+	req, err = o.load(p)
+	return req, true, err
+}
+
+// release 重新上报结束，remove为true且上报期间文件未被新的结果覆盖时删除文件
+func (o *Outbox) release(p string, remove bool) error {
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+	overwritten := outboxSending[p]
+	delete(outboxSending, p)
+	if !remove || overwritten {
+		return nil
+	}
+	return os.Remove(p)
+}
+
+// setAside 重命名无法解析的结果文件，保留用于排查问题
+func (o *Outbox) setAside(p string, cause error) error {
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+	overwritten := outboxSending[p]
+	delete(outboxSending, p)
+	if overwritten {
+		return nil
+	}
+	util.Warn("set aside malformed report %s: %s", filepath.Base(p), cause.Error())
+	return os.Rename(p, p+malformedFileExt)
+}
+
+// Len 获取待重新上报的结果数量
+func (o *Outbox) Len() int {
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+	matches, _ := filepath.Glob(filepath.Join(o.Dir, "*"+outboxFileExt))
+	return len(matches)
+}
+
+func (o *Outbox) load(p string) (*ReportResultRequest, error) {
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	req := new(ReportResultRequest)
+	if err := json.Unmarshal(content, req); err != nil {
+		return nil, fmt.Errorf("%w: %s", errMalformedReport, err.Error())
+	}
+	return req, nil
+}
+
+func (o *Outbox) path(subTaskId string) string {
+	return filepath.Join(o.Dir, safeFileName(subTaskId)+outboxFileExt)
+}
+
+// isRejected 服务端明确拒绝的上报请求重试也不会成功，不需要再保留
+// 401、403与429等错误在更换令牌或限流结束后重试可能成功，不视为拒绝
+func isRejected(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOutboxRedeliver(t *testing.T) {
+	// 关闭重试加快测试
+	defaultClient := util.DefaultClient
+	httpClient := util.CreateHttpClient(util.CreateTransport(nil))
+	httpClient.RetryMax = 0
+	util.SetDefault(httpClient)
+	defer util.SetDefault(defaultClient)
+
+	var lock sync.Mutex
+	available := false
+	var reported []ReportResultRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !available {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		req := ReportResultRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		reported = append(reported, req)
+	}))
+	defer server.Close()
+
+	args := &object.Arguments{Url: server.URL, Token: "token", TaskId: "task-1", OutboxDir: t.TempDir()}
+	client := NewClient(args, t.TempDir())
+
+	// 同一个子任务多次上报失败只保留最后一次的结果
+	for _, status := range []object.TaskStatus{object.StatusFailed, object.StatusSuccess} {
+		client.ToolInput = &object.ToolInput{TaskId: "task-1"}
+		err := client.Finish(func() {}, object.NewOutput(status, new(object.Result)))
+		var reportErr *ReportError
+		if !errors.As(err, &reportErr) || !reportErr.Saved {
+			t.Fatalf("expect saved report error, got %v", err)
+		}
+	}
+	if client.Outbox.Len() != 1 {
+		t.Fatalf("expect 1 report in outbox, got %d", client.Outbox.Len())
+	}
+
+	// 服务恢复后重新上报
+	lock.Lock()
+	available = true
+	lock.Unlock()
+	args.Token = "new-token"
+	client.RedeliverReports()
+	if client.Outbox.Len() != 0 {
+		t.Fatalf("expect empty outbox, got %d", client.Outbox.Len())
+	}
+	if len(reported) != 1 || reported[0].ScanStatus != object.StatusSuccess || reported[0].Token != "new-token" {
+		t.Fatalf("unexpected redelivered reports: %+v", reported)
+	}
+}
+
+func TestOutboxPath(t *testing.T) {
+	o := NewOutbox(t.TempDir())
+	for id, expected := range map[string]string{
+		"task-1":      "task-1.json",
+		"../../task":  ".._.._task.json",
+		"a\\b":        "a_b.json",
+		"..":          "_.json",
+		".":           "_.json",
+		"/etc/passwd": "_etc_passwd.json",
+	} {
+		if p := o.path(id); p != filepath.Join(o.Dir, expected) {
+			t.Errorf("subtask %s: expect %s, got %s", id, expected, p)
+		}
+	}
+}
+
+func TestOutboxSaveWhileRedelivering(t *testing.T) {
+	o := NewOutbox(t.TempDir())
+	if err := o.Save(&ReportResultRequest{SubTaskId: "task-1", ScanStatus: object.StatusFailed}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新上报时不持有锁，上报期间保存的新结果不会被删除
+	delivered, err := o.Redeliver(func(req *ReportResultRequest) error {
+		return o.Save(&ReportResultRequest{SubTaskId: req.SubTaskId, ScanStatus: object.StatusSuccess})
+	})
+	if err != nil || delivered != 1 {
+		t.Fatalf("expect 1 delivered, got %d, %v", delivered, err)
+	}
+	if o.Len() != 1 {
+		t.Fatalf("expect new report kept, got %d", o.Len())
+	}
+	req, err := o.load(o.path("task-1"))
+	if err != nil || req.ScanStatus != object.StatusSuccess {
+		t.Fatalf("expect new report, got %+v, %v", req, err)
+	}
+}
+
+func TestOutboxSaveWithoutToken(t *testing.T) {
+	o := NewOutbox(t.TempDir())
+	req := &ReportResultRequest{SubTaskId: "task-1", Token: "secret-token", ScanStatus: object.StatusSuccess}
+	if err := o.Save(req); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(o.path("task-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "secret-token") {
+		t.Fatalf("token saved to outbox: %s", content)
+	}
+	if req.Token != "secret-token" {
+		t.Fatal("token of original request should not be cleared")
+	}
+}
+
+func TestOutboxRedeliverRejected(t *testing.T) {
+	o := NewOutbox(t.TempDir())
+	statuses := map[string]int{"task-400": 400, "task-401": 401, "task-403": 403, "task-404": 404, "task-429": 429}
+	for id := range statuses {
+		if err := o.Save(&ReportResultRequest{SubTaskId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 结构正确但字段类型错误的文件
+	malformed := o.path("task-malformed")
+	if err := os.WriteFile(malformed, []byte(`{"subTaskId":1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = o.Redeliver(func(req *ReportResultRequest) error {
+		return newStatusError(statuses[req.SubTaskId], nil)
+	})
+	for id, status := range statuses {
+		_, err := os.Stat(o.path(id))
+		if kept := err == nil; kept != (status == 401 || status == 403 || status == 429) {
+			t.Errorf("report %s with status %d kept: %t", id, status, kept)
+		}
+	}
+	if _, err := os.Stat(malformed + malformedFileExt); err != nil {
+		t.Errorf("expect malformed report set aside: %v", err)
+	}
+	if o.Len() != 3 {
+		t.Errorf("expect 3 reports, got %d", o.Len())
+	}
+}
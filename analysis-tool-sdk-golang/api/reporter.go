@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"github.com/hashicorp/go-retryablehttp"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Reporter 分析结果上报器，用于输出或上报工具输出
+type Reporter interface {
+	// Report 输出或上报工具输出
+	Report(ctx context.Context, toolOutput *object.ToolOutput) error
+}
+
+// FileReporter 将工具输出写入文件
+type FileReporter struct {
+	Path string
+}
+
+// Report 将工具输出写入文件
+func (r *FileReporter) Report(_ context.Context, toolOutput *object.ToolOutput) error {
+	return util.WriteToFile(r.Path, toolOutput)
+}
+
+// StdoutReporter 将工具输出以格式化的json输出到Writer，默认为标准输出
+type StdoutReporter struct {
+	Writer io.Writer
+}
+
+// Report 输出工具输出
+func (r *StdoutReporter) Report(_ context.Context, toolOutput *object.ToolOutput) error {
+	w := r.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(toolOutput)
+}
+
+// AnalystReporter 上报工具输出到制品分析服务，上报失败时保存到Outbox等待重新上报
+type AnalystReporter struct {
+	Args   *object.Arguments
+	Outbox *Outbox
+}
+
+// Report 上报工具输出到制品分析服务，失败时返回*ReportError
+func (r *AnalystReporter) Report(ctx context.Context, toolOutput *object.ToolOutput) error {
+	result := StandardScanExecutorResult{"standard", toolOutput.Status, toolOutput}
+	req := &ReportResultRequest{
+		SubTaskId:          toolOutput.TaskId,
+		ScanStatus:         toolOutput.Status,
+		ScanExecutorResult: &result,
+		Token:              r.Args.Token,
+	}
+	err := r.send(ctx, req)
+	if err == nil {
+		return nil
+	}
+	reportErr := &ReportError{TaskId: toolOutput.TaskId, Err: err}
+	if r.Outbox != nil {
+		if saveErr := r.Outbox.Save(req); saveErr != nil {
+			util.Error("save report of task %s to outbox failed: %s", toolOutput.TaskId, saveErr.Error())
+		} else {
+			reportErr.Saved = true
+		}
+	}
+	return reportErr
+}
+
+// Redeliver 重新上报Outbox中保存的结果
+func (r *AnalystReporter) Redeliver(ctx context.Context) {
+	if r.Outbox == nil {
+		return
+	}
+	delivered, err := r.Outbox.Redeliver(func(req *ReportResultRequest) error {
+		// 使用当前令牌上报
+		req.Token = r.Args.Token
+		return r.send(ctx, req)
+	})
+	if delivered > 0 {
+		util.Info("redeliver %d reports success", delivered)
+	}
+	if err != nil {
+		util.Error("redeliver reports failed: %s", err.Error())
+	}
+}
+
+func (r *AnalystReporter) send(ctx context.Context, reportReq *ReportResultRequest) error {
+	reqUrl := r.Args.Url + analystTemporaryPrefix + "/scan/report"
+	return postJson(ctx, reqUrl, reportReq, nil)
+}
+
+// WebhookReporter 以json格式POST工具输出到指定地址
+type WebhookReporter struct {
+	Url     string
+	Headers map[string]string
+}
+
+// Report 推送工具输出
+func (r *WebhookReporter) Report(ctx context.Context, toolOutput *object.ToolOutput) error {
+	return postJson(ctx, r.Url, toolOutput, r.Headers)
+}
+
+// FanoutReporter 依次使用所有Reporter上报，某个Reporter失败时不影响其他Reporter
+type FanoutReporter []Reporter
+
+// Report 使用所有Reporter上报，返回所有失败的错误
+func (r FanoutReporter) Report(ctx context.Context, toolOutput *object.ToolOutput) error {
+	var errs []error
+	for _, reporter := range r {
+		if err := reporter.Report(ctx, toolOutput); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewReporter 根据参数创建Reporter，args.Reporters为空时使用当前运行模式的默认Reporter
+func NewReporter(args *object.Arguments, outbox *Outbox) Reporter {
+	names := args.Reporters
+	if len(names) == 0 {
+		names = []string{object.ReporterDefault}
+	}
+	reporters := make(FanoutReporter, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case object.ReporterDefault:
+			reporters = append(reporters, defaultReporter(args, outbox))
+		case object.ReporterFile:
+			reporters = append(reporters, &FileReporter{Path: args.OutputFilePath})
+		case object.ReporterStdout:
+			reporters = append(reporters, &StdoutReporter{})
+		case object.ReporterAnalyst:
+			reporters = append(reporters, &AnalystReporter{Args: args, Outbox: outbox})
+		case object.ReporterWebhook:
+			reporters = append(reporters, &WebhookReporter{Url: args.WebhookUrl, Headers: args.WebhookHeaders})
+		}
+	}
+	if len(reporters) == 1 {
+		return reporters[0]
+	}
+	return reporters
+}
+
+// defaultReporter 离线模式输出到文件，--file模式未指定输出文件时输出到标准输出，其他情况上报到制品分析服务
+func defaultReporter(args *object.Arguments, outbox *Outbox) Reporter {
+	if args.Offline() || args.ScanFile() && args.OutputFilePath != "" {
+		return &FileReporter{Path: args.OutputFilePath}
+	}
+	if args.ScanFile() {
+		return &StdoutReporter{}
+	}
+	return &AnalystReporter{Args: args, Outbox: outbox}
+}
+
+func postJson(ctx context.Context, url string, body any, headers map[string]string) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json; charset=UTF-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := util.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer util.DrainBody(res.Body)
+	if res.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return newStatusError(res.StatusCode, errBody)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFanoutReporter(t *testing.T) {
+	var received *object.ToolOutput
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Token")
+		received = new(object.ToolOutput)
+		_ = json.NewDecoder(r.Body).Decode(received)
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "output.json")
+	args := &object.Arguments{
+		InputFilePath:  "input.json",
+		OutputFilePath: output,
+		Reporters:      object.ReporterFlags{object.ReporterDefault, object.ReporterWebhook, object.ReporterStdout},
+		WebhookUrl:     server.URL,
+		WebhookHeaders: object.HeaderFlags{"X-Token": "secret"},
+	}
+	reporter := NewReporter(args, nil)
+	stdout := new(bytes.Buffer)
+	reporter.(FanoutReporter)[2].(*StdoutReporter).Writer = stdout
+
+	client := NewClient(args, t.TempDir())
+	client.Reporter = reporter
+	client.ToolInput = &object.ToolInput{TaskId: "task-1"}
+	if err := client.Finish(func() {}, object.NewOutput(object.StatusSuccess, new(object.Result))); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		t.Fatal(err.Error())
+	}
+	if received == nil || received.TaskId != "task-1" || token != "secret" {
+		t.Fatalf("unexpected webhook request: %+v, token: %s", received, token)
+	}
+	stdoutOutput := new(object.ToolOutput)
+	if err := json.Unmarshal(stdout.Bytes(), stdoutOutput); err != nil || stdoutOutput.Status != object.StatusSuccess {
+		t.Fatalf("unexpected stdout: %s", stdout.String())
+	}
+}
@@ -0,0 +1,230 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// batchInputFileName 批量扫描目录时查找的输入文件名
+const batchInputFileName = "input.json"
+
+// batchSummaryFileName 批量扫描汇总结果文件名
+const batchSummaryFileName = "summary.json"
+
+// BatchSummary 批量离线扫描的汇总结果
+type BatchSummary struct {
+	Total    int                       `json:"total"`
+	Statuses map[object.TaskStatus]int `json:"statuses"`
+	Items    []BatchItem               `json:"items"`
+}
+
+// BatchItem 单个输入文件的扫描结果
+type BatchItem struct {
+	Input          string            `json:"input"`
+	Output         string            `json:"output"`
+	TaskId         string            `json:"taskId"`
+	Status         object.TaskStatus `json:"status"`
+	Err            string            `json:"err,omitempty"`
+	SecurityCount  int               `json:"securityCount"`
+	LicenseCount   int               `json:"licenseCount"`
+	SensitiveCount int               `json:"sensitiveCount"`
+}
+
+// runBatch 批量执行离线扫描，单个输入失败时继续扫描其他输入，最后输出汇总结果
+func runBatch(ctx context.Context, executor *managedExecutor, args *object.Arguments) error {
+	root, inputs, err := findBatchInputs(args.BatchInput)
+	if err != nil {
+		return err
+	}
+	outputRoot := root
+	if args.OutputDir != "" {
+		outputRoot = args.OutputDir
+	}
+	util.Info("found %d inputs in %s", len(inputs), args.BatchInput)
+
+	items := make([]BatchItem, len(inputs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < max(args.Parallel, 1); i++ {
+		// 不同输入文件的任务id可能相同，每个goroutine使用独立的工作空间根目录
+		workDir := filepath.Join(api.WorkRoot(args), "batch-"+strconv.Itoa(i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				output, err := batchOutputPath(root, outputRoot, inputs[index])
+				if err != nil {
+					items[index] = BatchItem{Input: inputs[index], Status: object.StatusFailed, Err: err.Error()}
+					continue
+				}
+				items[index] = runBatchItem(ctx, executor, args, inputs[index], output, workDir)
+			}
+		}()
+	}
+
+	for i := range inputs {
+		if ctx.Err() != nil {
+			items[i] = BatchItem{Input: inputs[i], Status: object.StatusStopped, Err: "analysis stopped"}
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	summary := &BatchSummary{Total: len(items), Statuses: make(map[object.TaskStatus]int), Items: items}
+	for _, item := range items {
+		summary.Statuses[item.Status]++
+	}
+	summaryPath := filepath.Join(outputRoot, batchSummaryFileName)
+	if err := writeJsonFile(summaryPath, summary); err != nil {
+		return err
+	}
+	util.Info("batch analyze finished, summary: %s, statuses: %v", summaryPath, summary.Statuses)
+	return nil
+}
+
+// runBatchItem 执行单个输入文件的离线扫描
+func runBatchItem(
+	stopCtx context.Context,
+	executor *managedExecutor,
+	args *object.Arguments,
+	input string,
+	output string,
+	workDir string,
+) BatchItem {
+	item := BatchItem{Input: input, Output: output}
+	defer func() {
+		if err := util.CleanDir(workDir); err != nil {
+			util.Error("clean workdir %s failed: %s", workDir, err.Error())
+		}
+	}()
+
+	itemArgs := *args
+	itemArgs.BatchInput = ""
+	itemArgs.InputFilePath = input
+	itemArgs.OutputFilePath = output
+	client := api.NewClient(&itemArgs, workDir)
+	ctx, cancel := context.WithCancel(stopCtx)
+	defer cancel()
+
+	toolInput, err := client.Start(ctx, cancel)
+	if err == nil && toolInput == nil {
+		err = errors.New("taskId not found")
+	}
+	if err != nil {
+		util.Error("load input %s failed: %s", input, err.Error())
+		item.Status = object.StatusFailed
+		item.Err = err.Error()
+		// 输入加载失败时同样输出FAILED结果，使每个输入都有对应的输出文件
+		if err := reportBatchItem(client, output, object.NewFailedOutput(err)); err != nil {
+			util.Error("write output %s failed: %s", output, err.Error())
+		}
+		return item
+	}
+
+	util.Info("analyze %s", input)
+	clearToolInput := shareToolInput(args, toolInput)
+	toolOutput := runTask(ctx, stopCtx, executor, client)
+	clearToolInput()
+	item.TaskId = toolInput.TaskId
+	item.Status = toolOutput.Status
+	item.Err = toolOutput.Err
+	if toolOutput.Result != nil {
+		item.SecurityCount = len(toolOutput.Result.SecurityResults)
+		item.LicenseCount = len(toolOutput.Result.LicenseResults)
+		item.SensitiveCount = len(toolOutput.Result.SensitiveResults)
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0766); err != nil {
+		item.Err = err.Error()
+	} else if err := client.Finish(cancel, toolOutput); err != nil {
+		item.Err = err.Error()
+	}
+	return item
+}
+
+// reportBatchItem 输出未能开始扫描的输入对应的结果
+func reportBatchItem(client *api.BkRepoClient, output string, toolOutput *object.ToolOutput) error {
+	if err := os.MkdirAll(filepath.Dir(output), 0766); err != nil {
+		return err
+	}
+	return client.Reporter.Report(context.Background(), toolOutput)
+}
+
+// findBatchInputs 查找批量扫描的输入文件，返回输入文件的根目录与输入文件列表
+// pattern为目录时递归查找目录下所有input.json，否则作为glob匹配输入文件
+func findBatchInputs(pattern string) (string, []string, error) {
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		var inputs []string
+		err := filepath.WalkDir(pattern, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && d.Name() == batchInputFileName {
+				inputs = append(inputs, path)
+			}
+			return nil
+		})
+		return pattern, inputs, err
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	// 忽略之前批量扫描输出的文件
+	inputs := make([]string, 0, len(matches))
+	for _, m := range matches {
+		name := filepath.Base(m)
+		if name != batchSummaryFileName && name != "output.json" && !strings.HasSuffix(name, ".output.json") {
+			inputs = append(inputs, m)
+		}
+	}
+	return globRoot(pattern), inputs, nil
+}
+
+// globRoot 获取glob中不包含通配符的目录部分
+func globRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// batchOutputPath 获取输入文件对应的输出文件路径，保持输入文件相对于根目录的路径结构
+// input.json对应output.json，其他文件名xxx.json对应xxx.output.json
+func batchOutputPath(root string, outputRoot string, input string) (string, error) {
+	rel, err := filepath.Rel(root, input)
+	if err != nil {
+		return "", err
+	}
+	dir, name := filepath.Split(rel)
+	if name == batchInputFileName {
+		name = "output.json"
+	} else {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ".output.json"
+	}
+	return filepath.Join(outputRoot, dir, name), nil
+}
+
+func writeJsonFile(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0766); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,118 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	for _, taskId := range []string{"task-1", "task-2"} {
+		content, _ := json.Marshal(newTestToolInput(t, taskId))
+		writeTestFile(t, filepath.Join(inputDir, taskId, "input.json"), content)
+	}
+	writeTestFile(t, filepath.Join(inputDir, "broken", "input.json"), []byte("{"))
+
+	executor := newManagedExecutor(resultExecutor(new(object.Result)))
+	args := &object.Arguments{BatchInput: inputDir, OutputDir: outputDir, Parallel: 2}
+	if err := runBatch(context.Background(), executor, args); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, taskId := range []string{"task-1", "task-2"} {
+		content, err := os.ReadFile(filepath.Join(outputDir, taskId, "output.json"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		output := new(object.ToolOutput)
+		if err := json.Unmarshal(content, output); err != nil || output.TaskId != taskId {
+			t.Fatalf("unexpected output of %s: %s", taskId, string(content))
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "broken", "output.json"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	output := new(object.ToolOutput)
+	if err := json.Unmarshal(content, output); err != nil || output.Status != object.StatusFailed || output.Err == "" {
+		t.Fatalf("unexpected output of broken input: %s", string(content))
+	}
+
+	content, err = os.ReadFile(filepath.Join(outputDir, "summary.json"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	summary := new(BatchSummary)
+	if err := json.Unmarshal(content, summary); err != nil {
+		t.Fatal(err.Error())
+	}
+	if summary.Total != 3 || summary.Statuses[object.StatusSuccess] != 2 || summary.Statuses[object.StatusFailed] != 1 {
+		t.Fatalf("unexpected summary: %s", string(content))
+	}
+}
+
+func TestRunBatchGlobalClient(t *testing.T) {
+	inputDir := t.TempDir()
+	content, _ := json.Marshal(newTestToolInput(t, "task-1"))
+	writeTestFile(t, filepath.Join(inputDir, "input.json"), content)
+	// 兼容通过全局客户端获取任务的工具
+	var args *object.Arguments
+	executor := ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		if toolInput := api.GetClient(args).ToolInput; toolInput == nil || toolInput.TaskId != "task-1" {
+			return nil, errors.New("unexpected global tool input")
+		}
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+
+	expected := map[int]string{1: "", 2: "api.GetClient"}
+	for parallel, errContains := range expected {
+		args = &object.Arguments{BatchInput: inputDir, Parallel: parallel}
+		if err := analyze(context.Background(), executor, args); err != nil {
+			t.Fatal(err.Error())
+		}
+		content, err := os.ReadFile(filepath.Join(inputDir, "output.json"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		output := new(object.ToolOutput)
+		_ = json.Unmarshal(content, output)
+		if errContains == "" && output.Status != object.StatusSuccess ||
+			errContains != "" && (output.Status != object.StatusFailed || !strings.Contains(output.Err, errContains)) {
+			t.Fatalf("unexpected output with parallel %d: %s", parallel, string(content))
+		}
+	}
+}
+
+func TestBatchOutputPath(t *testing.T) {
+	cases := map[string]string{
+		"/in/a/input.json": "/out/a/output.json",
+		"/in/b.json":       "/out/b.output.json",
+	}
+	for input, expected := range cases {
+		output, err := batchOutputPath("/in", "/out", input)
+		if err != nil || output != expected {
+			t.Fatalf("expect %s, got %s", expected, output)
+		}
+	}
+	if root := globRoot("/in/*/input-*.json"); root != "/in" {
+		t.Fatalf("unexpected glob root %s", root)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0766); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,267 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CompositeExecutor 在同一个待分析文件上执行多个执行器并合并去重扫描结果
+// 部分执行器失败时仍会返回其他执行器的结果，失败信息记录在ToolOutput.Err中
+type CompositeExecutor struct {
+	// Executors 需要执行的执行器
+	Executors []Executor
+	// Parallel 是否并行执行
+	Parallel bool
+	// Spec 组合后工具的名称、镜像等描述信息，参数与支持的类型会合并所有执行器声明的值，可以为nil
+	Spec *ToolSpec
+}
+
+// NewCompositeExecutor 创建CompositeExecutor
+func NewCompositeExecutor(parallel bool, executors ...Executor) *CompositeExecutor {
+	return &CompositeExecutor{Executors: executors, Parallel: parallel}
+}
+
+// childResult 单个执行器的执行结果
+type childResult struct {
+	output *object.ToolOutput
+	err    error
+}
+
+// Execute 执行所有执行器并合并结果，所有执行器都失败时返回error
+func (e *CompositeExecutor) Execute(
+	ctx context.Context,
+	config *object.ToolConfig,
+	file *os.File,
+) (*object.ToolOutput, error) {
+	results := make([]childResult, len(e.Executors))
+	if e.Parallel {
+		var wg sync.WaitGroup
+		for i := range e.Executors {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = e.executeChild(ctx, i, config, file)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range e.Executors {
+			results[i] = e.executeChild(ctx, i, config, file)
+		}
+	}
+
+	merger := newResultMerger()
+	var errs []string
+	succeed := 0
+	for i, r := range results {
+		if r.output != nil {
+			merger.merge(r.output.Result)
+		}
+		if r.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", e.childName(i), r.err.Error()))
+		} else {
+			succeed++
+		}
+	}
+
+	output := object.NewOutput(object.StatusSuccess, merger.result)
+	output.Err = strings.Join(errs, "\n")
+	if ctx.Err() != nil {
+		// 超时或被中止时返回已合并的部分结果
+		if len(errs) == 0 {
+			return output, ctx.Err()
+		}
+		return output, fmt.Errorf("%w\n%s", ctx.Err(), output.Err)
+	}
+	if succeed == 0 && len(errs) > 0 {
+		return nil, errors.New(output.Err)
+	}
+	return output, nil
+}
+
+// Init 初始化实现了Lifecycle接口的执行器，某个执行器初始化失败时按相反顺序关闭已初始化的执行器
+func (e *CompositeExecutor) Init(ctx context.Context, config *object.ToolConfig) error {
+	for i, executor := range e.Executors {
+		lifecycle, ok := implements[Lifecycle](executor)
+		if !ok {
+			continue
+		}
+		if err := lifecycle.Init(ctx, config); err != nil {
+			if closeErr := e.closeChildren(i); closeErr != nil {
+				util.ErrorContext(ctx, "close executors failed: %s", closeErr.Error())
+			}
+			return fmt.Errorf("init %s failed: %w", e.childName(i), err)
+		}
+	}
+	return nil
+}
+
+// Describe 合并所有实现了Describer的执行器的描述信息，参数按键去重，工具名、镜像等使用Spec中的值
+func (e *CompositeExecutor) Describe() *ToolSpec {
+	spec := new(ToolSpec)
+	if e.Spec != nil {
+		*spec = *e.Spec
+		spec.Args = slices.Clone(e.Spec.Args)
+		spec.SupportFileNameExt = slices.Clone(e.Spec.SupportFileNameExt)
+		spec.SupportPackageTypes = slices.Clone(e.Spec.SupportPackageTypes)
+		spec.SupportScanTypes = slices.Clone(e.Spec.SupportScanTypes)
+	}
+	// 任意一个执行器支持所有包类型时组合后也支持所有包类型
+	allPackageTypes := e.Spec == nil || len(e.Spec.SupportPackageTypes) == 0
+	for _, executor := range e.Executors {
+		describer, ok := implements[Describer](executor)
+		if !ok {
+			allPackageTypes = true
+			continue
+		}
+		child := describer.Describe()
+		for _, arg := range child.Args {
+			if !slices.ContainsFunc(spec.Args, func(a ArgSpec) bool { return a.Key == arg.Key }) {
+				spec.Args = append(spec.Args, arg)
+			}
+		}
+		spec.SupportFileNameExt = union(spec.SupportFileNameExt, child.SupportFileNameExt)
+		spec.SupportScanTypes = union(spec.SupportScanTypes, child.SupportScanTypes)
+		if len(child.SupportPackageTypes) == 0 {
+			allPackageTypes = true
+		}
+		spec.SupportPackageTypes = union(spec.SupportPackageTypes, child.SupportPackageTypes)
+	}
+	if allPackageTypes {
+		spec.SupportPackageTypes = nil
+	}
+	return spec
+}
+
+// Validate 使用实现了Validator的执行器校验工具配置，Describer声明的必填参数通过Describe合并后校验
+func (e *CompositeExecutor) Validate(config *object.ToolConfig) error {
+	var errs []error
+	for i, executor := range e.Executors {
+		if validator, ok := implements[Validator](executor); ok {
+			if err := validator.Validate(config); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", e.childName(i), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭实现了Lifecycle接口的执行器
+func (e *CompositeExecutor) Close() error {
+	return e.closeChildren(len(e.Executors))
+}
+
+// closeChildren 按相反顺序关闭前n个实现了Lifecycle接口的执行器
+func (e *CompositeExecutor) closeChildren(n int) error {
+	var errs []error
+	for i := n - 1; i >= 0; i-- {
+		if lifecycle, ok := implements[Lifecycle](e.Executors[i]); ok {
+			if err := lifecycle.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close %s failed: %w", e.childName(i), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// executeChild 执行单个执行器，每个执行器使用独立打开的文件，避免共享文件读取位置
+func (e *CompositeExecutor) executeChild(
+	ctx context.Context,
+	i int,
+	config *object.ToolConfig,
+	file *os.File,
+) childResult {
+	f, err := os.Open(file.Name())
+	if err != nil {
+		return childResult{err: err}
+	}
+	defer f.Close()
+
+	util.InfoContext(ctx, "execute %s", e.childName(i))
+	output, err := Chain(e.Executors[i], Recover()).Execute(ctx, config, f)
+	if err == nil && output == nil {
+		err = errors.New("executor returned nil output")
+	}
+	if err == nil && output.Status != object.StatusSuccess {
+		err = fmt.Errorf("status %s, err: %s", output.Status, output.Err)
+	}
+	if err != nil {
+		util.ErrorContext(ctx, "execute %s failed: %s", e.childName(i), err.Error())
+	}
+	return childResult{output: output, err: err}
+}
+
+// childName 获取执行器名称用于日志与错误信息，执行器实现了Describer时带上声明的工具名
+func (e *CompositeExecutor) childName(i int) string {
+	if describer, ok := implements[Describer](e.Executors[i]); ok && describer.Describe().Name != "" {
+		return fmt.Sprintf("executor[%d](%s)", i, describer.Describe().Name)
+	}
+	return fmt.Sprintf("executor[%d]", i)
+}
+
+// resultMerger 合并多个扫描结果并去重
+type resultMerger struct {
+	result    *object.Result
+	security  map[string]struct{}
+	license   map[string]struct{}
+	sensitive map[string]struct{}
+}
+
+func newResultMerger() *resultMerger {
+	return &resultMerger{
+		result:    NormalizeResult(nil),
+		security:  make(map[string]struct{}),
+		license:   make(map[string]struct{}),
+		sensitive: make(map[string]struct{}),
+	}
+}
+
+func (m *resultMerger) merge(result *object.Result) {
+	if result == nil {
+		return
+	}
+	for _, r := range result.SecurityResults {
+		key := strings.Join([]string{r.VulId, r.CveId, r.PkgName, r.Path, strings.Join(r.PkgVersions, ",")}, "|")
+		if add(m.security, key) {
+			m.result.SecurityResults = append(m.result.SecurityResults, r)
+		}
+	}
+	for _, r := range result.LicenseResults {
+		key := strings.Join([]string{r.LicenseName, r.Path, r.PkgName, r.PkgVersion}, "|")
+		if add(m.license, key) {
+			m.result.LicenseResults = append(m.result.LicenseResults, r)
+		}
+	}
+	for _, r := range result.SensitiveResults {
+		key := strings.Join([]string{r.Path, r.Type, r.Content}, "|")
+		if add(m.sensitive, key) {
+			m.result.SensitiveResults = append(m.result.SensitiveResults, r)
+		}
+	}
+}
+
+// union 合并两个列表并去重，保持原有顺序
+func union(values []string, others []string) []string {
+	for _, v := range others {
+		if !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// add 添加key到集合中，key已存在时返回false
+func add(set map[string]struct{}, key string) bool {
+	if _, ok := set[key]; ok {
+		return false
+	}
+	set[key] = struct{}{}
+	return true
+}
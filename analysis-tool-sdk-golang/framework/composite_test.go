@@ -0,0 +1,111 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompositeExecutor(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(f, []byte("image"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	file, err := os.Open(f)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer file.Close()
+
+	vul := object.SecurityResult{VulId: "CVE-2023-0001", PkgName: "openssl", PkgVersions: []string{"1.0"}}
+	security := resultExecutor(&object.Result{SecurityResults: []object.SecurityResult{vul}})
+	license := resultExecutor(&object.Result{
+		SecurityResults: []object.SecurityResult{vul},
+		LicenseResults:  []object.LicenseResult{{LicenseName: "MIT", PkgName: "openssl"}},
+	})
+	failed := ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		return nil, errors.New("scan failed")
+	})
+
+	for _, parallel := range []bool{false, true} {
+		output, err := NewCompositeExecutor(parallel, security, license, failed).
+			Execute(context.Background(), &object.ToolConfig{}, file)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(output.Result.SecurityResults) != 1 || len(output.Result.LicenseResults) != 1 {
+			t.Fatalf("unexpected merged result: %+v", output.Result)
+		}
+		if !strings.Contains(output.Err, "scan failed") {
+			t.Fatalf("expect child failure in err, got %s", output.Err)
+		}
+	}
+
+	if _, err := NewCompositeExecutor(false, failed).Execute(context.Background(), &object.ToolConfig{}, file); err == nil {
+		t.Fatal("expect error when all executors failed")
+	}
+}
+
+func resultExecutor(result *object.Result) Executor {
+	return ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		return object.NewOutput(object.StatusSuccess, result), nil
+	})
+}
+
+func TestCompositeExecutorInitFailed(t *testing.T) {
+	first, second := new(lifecycleExecutor), new(lifecycleExecutor)
+	failed := &failedInitExecutor{lifecycleExecutor: new(lifecycleExecutor)}
+	composite := NewCompositeExecutor(false, first, second, failed)
+	if err := composite.Init(context.Background(), new(object.ToolConfig)); err == nil {
+		t.Fatal("expect init failed")
+	}
+	if first.closed != 1 || second.closed != 1 || failed.closed != 0 {
+		t.Fatalf("expect initialized executors closed, got %d %d %d", first.closed, second.closed, failed.closed)
+	}
+}
+
+// failedInitExecutor Init总是失败的执行器
+type failedInitExecutor struct {
+	*lifecycleExecutor
+}
+
+func (e *failedInitExecutor) Init(_ context.Context, _ *object.ToolConfig) error {
+	return errors.New("init failed")
+}
+
+func TestCompositeExecutorValidate(t *testing.T) {
+	validating := &validatingExecutor{Executor: resultExecutor(nil)}
+	composite := NewCompositeExecutor(false, AdaptTaskExecutor(&describedExecutor{}), validating)
+	composite.Spec = &ToolSpec{Name: "composite"}
+	if name := composite.childName(0); name != "executor[0](scanner)" {
+		t.Fatalf("unexpected child name %s", name)
+	}
+	if name := composite.childName(1); name != "executor[1]" {
+		t.Fatalf("unexpected child name %s", name)
+	}
+
+	spec := composite.Describe()
+	if spec.Name != "composite" || len(spec.Args) != 2 || len(spec.SupportPackageTypes) != 0 {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+
+	// 组合执行器中的必填参数与Validator同样生效
+	err := newManagedExecutor(composite).validate(&object.ToolConfig{})
+	if err == nil || !strings.Contains(err.Error(), "missing required arg dbUrl") ||
+		!strings.Contains(err.Error(), "executor[1]: invalid config") {
+		t.Fatalf("unexpected validate result: %v", err)
+	}
+}
+
+// validatingExecutor 工具配置校验总是失败的执行器
+type validatingExecutor struct {
+	Executor
+}
+
+func (e *validatingExecutor) Validate(_ *object.ToolConfig) error {
+	return errors.New("invalid config")
+}
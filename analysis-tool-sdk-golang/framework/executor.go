@@ -8,69 +8,283 @@ import (
 	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
 	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // Executor 分析执行器
 type Executor interface {
 	// Execute 框架会调用该函数执行扫描，传入的参数config为工具相关配置，file为待分析的制品
 	// 扫描成功时返回toolOutput，出错时返回error，工具框架会自动上报或输出结果给制品分析服务
+	// ctx超过maxTime结束时，可以同时返回包含已扫描出的部分结果的toolOutput和error，框架会以TIMEOUT状态上报部分结果
+	// 可以通过TaskFromContext(ctx)获取任务id、包类型、工作空间等任务上下文，也可以实现TaskExecutor后通过AdaptTaskExecutor转换
 	Execute(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error)
 }
 
-// Analyze 执行分析
-func Analyze(executor Executor) {
+// Analyze 执行分析，指定--print-tool-json时仅输出tool.json
+// 收到SIGTERM或SIGINT信号后不再拉取新任务，并中止正在执行的任务上报STOPPED状态，
+// 超过args.GracePeriod仍未结束时直接退出进程
+func Analyze(executor Executor, opts ...Option) {
 	args := object.GetArgs()
-	for {
-		util.Info("start analyze")
-		doAnalyze(executor, args)
-		util.Info("keep running %t", args.ShouldKeepRunning())
-		if args.ShouldKeepRunning() {
-			if err := util.CleanWorkDir(); err != nil {
-				panic("clean work dir failed: " + err.Error())
-			}
-			util.Info("clean workdir success")
-		} else {
-			util.Info("analyze finished")
-			break
+	util.AddSecrets(args.Secrets()...)
+	name := toolName(executor)
+	if err := util.InitLogger(args.LogFormat, args.LogLevel, "tool", name); err != nil {
+		util.Error("init logger failed: %s", err.Error())
+		os.Exit(1)
+	}
+	if args.PrintToolJson {
+		if err := PrintToolJson(os.Stdout, executor); err != nil {
+			util.Error("print tool.json failed: %s", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	util.DefaultMetrics.SetConstLabels(map[string]string{"tool": name, "execution_cluster": args.ExecutionCluster})
+	if args.MetricsListen != "" {
+		stopMetrics := serveMetrics(args.MetricsListen)
+		defer stopMetrics()
+	}
+	if args.ProbeListen != "" && args.ProbeListen != args.MetricsListen {
+		stopProbes := serveProbes(args.ProbeListen)
+		defer stopProbes()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	var err error
+	done := make(chan struct{})
+	go func() {
+		err = analyze(ctx, executor, args, opts...)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		util.Warn("received stop signal, wait %ds for running tasks", args.GracePeriod)
+		select {
+		case <-done:
+		case <-time.After(time.Duration(args.GracePeriod) * time.Second):
+			util.Error("running tasks did not stop within %ds, exit", args.GracePeriod)
+			os.Exit(1)
 		}
 	}
+	if err != nil {
+		util.Error("analyze failed: %s", err.Error())
+		os.Exit(1)
+	}
+	util.Info("analyze finished")
+}
+
+// toolName 获取工具名，执行器实现了Describer时使用声明的工具名，否则使用可执行文件名
+func toolName(executor Executor) string {
+	if describer, ok := implements[Describer](executor); ok && describer.Describe().Name != "" {
+		return describer.Describe().Name
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// analyze 启动worker执行分析或启动HTTP服务，ctx结束后worker不再拉取新任务
+// 仅在非keep-running模式下返回任务执行过程中的错误，keep-running模式下出错时会继续执行下一个任务
+func analyze(ctx context.Context, e Executor, args *object.Arguments, opts ...Option) error {
+	o := newOptions(opts)
+	executor := newManagedExecutor(e, o.middlewares...)
+	defer executor.close()
+	defer probes.start(ctx, executor)()
+	if !singleTask(args) {
+		// 同时执行多个任务时全局客户端中的ToolInput不是当前任务，禁止工具通过api.GetClient获取任务
+		defer api.RejectGlobalClient()()
+	}
+	if args.Serve() {
+		return serve(ctx, executor, args)
+	}
+	if args.Batch() {
+		return runBatch(ctx, executor, args)
+	}
+	r := newRecycler(ctx, args)
+	defer r.cancel()
+	if args.ShouldKeepRunning() {
+		go r.watch()
+	}
+	workerCount := args.WorkerCount()
+	if workerCount == 1 {
+		w := newWorker(0, executor, singleWorkerClient(args), r)
+		w.cleanRoot = true
+		return w.run(ctx)
+	}
+
+	// 每个worker使用独立的客户端，任务的工作空间以任务id区分，不会相互影响
+	util.Info("start %d workers", workerCount)
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		w := newWorker(i, executor, api.NewClient(args, api.WorkRoot(args)), r)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = w.run(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// singleWorkerClient 获取单个worker使用的客户端，使用全局客户端以兼容通过api.GetClient获取任务的工具
+// 全局客户端只会创建一次，同一进程中使用不同参数再次执行分析时（例如测试中）创建新的客户端
+func singleWorkerClient(args *object.Arguments) *api.BkRepoClient {
+	if client := api.GetClient(args); client.Args == args {
+		return client
+	}
+	return api.NewClient(args, api.WorkRoot(args))
+}
+
+// shareToolInput 同一时间只执行一个任务时将任务输入设置到全局客户端，兼容通过api.GetClient获取任务的工具
+// 返回的函数用于任务结束后清除全局客户端中的任务输入
+func shareToolInput(args *object.Arguments, toolInput *object.ToolInput) func() {
+	if !singleTask(args) {
+		return func() {}
+	}
+	global := api.GetClient(args)
+	global.ToolInput = toolInput
+	return func() { global.ToolInput = nil }
 }
 
-func doAnalyze(executor Executor, arguments *object.Arguments) {
-	client := api.GetClient(arguments)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	input, err := client.Start(ctx, cancel)
+// runTask 在任务工作空间中执行client中的任务，返回需要上报的工具输出，任务结束后删除任务工作空间
+// ctx为任务上下文，stopCtx结束时表示任务被中止
+func runTask(
+	ctx context.Context,
+	stopCtx context.Context,
+	executor *managedExecutor,
+	client *api.BkRepoClient,
+) (output *object.ToolOutput) {
+	defer func() { util.MetricTasksFinished.Inc(string(output.Status)) }()
+	input := client.ToolInput
+	workDir := client.TaskWorkDir()
+	task := newTask(input, workDir)
+	ctx = withTask(ctx, task)
+	if client.Progress == nil {
+		client.Progress = util.NewProgressTracker()
+	}
+	ctx = util.WithProgress(ctx, client.Progress)
+	if stopCtx.Err() != nil {
+		return stoppedOutput(ctx)
+	}
+	if err := executor.validate(&input.ToolConfig); err != nil {
+		return failedOutput(ctx, errors.New("Validate tool config failed: "+err.Error()))
+	}
+	defer func() {
+		if err := util.CleanDir(workDir); err != nil {
+			util.ErrorContext(ctx, "clean workdir %s failed: %s", workDir, err.Error())
+		}
+	}()
+	if err := os.MkdirAll(workDir, 0766); err != nil {
+		return failedOutput(ctx, errors.New("Create workdir failed: "+err.Error()))
+	}
+
+	taskLog, err := newTaskLog(client.Args, task)
 	if err != nil {
-		panic("Start analyze failed: " + err.Error())
+		util.ErrorContext(ctx, "create task log failed: %s", err.Error())
+	} else if taskLog != nil {
+		ctx = withTask(ctx, task)
 	}
-	if input == nil || input.TaskId == "" {
-		util.Info("no subtask found, exit")
-		return
+	output = executeTask(ctx, stopCtx, executor, client)
+	if taskLog != nil {
+		taskLog.finish(ctx, client.Args, output)
 	}
-	file, err := client.GenerateInputFile()
+	return output
+}
+
+// executeTask 生成待分析文件并执行分析
+func executeTask(
+	ctx context.Context,
+	stopCtx context.Context,
+	executor *managedExecutor,
+	client *api.BkRepoClient,
+) *object.ToolOutput {
+	input := client.ToolInput
+	release, err := executor.prepare(stopCtx, &input.ToolConfig)
 	if err != nil {
-		client.Failed(cancel, errors.New("Generate input file failed: "+err.Error()))
-		return
+		if stopCtx.Err() != nil {
+			return stoppedOutput(ctx)
+		}
+		return failedOutput(ctx, errors.New("Init executor failed: "+err.Error()))
+	}
+	defer release()
+	file, err := client.GenerateInputFileContext(ctx)
+	if err != nil {
+		if stopCtx.Err() != nil {
+			return stoppedOutput(ctx)
+		}
+		return failedOutput(ctx, errors.New("Generate input file failed: "+err.Error()))
 	}
 	// 返回的file为nil时表示文件被忽略，直接返回
 	if file == nil {
-		util.Info("Unsupported filename: %s", input.FileUrls[0].Name)
-		client.Finish(cancel, object.NewOutput(object.StatusSuccess, new(object.Result)))
-		return
+		util.InfoContext(ctx, "Unsupported filename: %s", input.FileUrls[0].Name)
+		return object.NewOutput(object.StatusSuccess, new(object.Result))
 	}
 	defer file.Close()
-	util.Info("generate input file success")
-	execCtx, execCancel := context.WithTimeout(ctx, client.ToolInput.MaxTime())
+	util.InfoContext(ctx, "generate input file success")
+	util.ReportProgress(ctx, util.ProgressStageScanning, 0, nil)
+	execCtx, execCancel := withMaxTime(ctx, input.MaxTime())
 	defer execCancel()
-	output, err := executor.Execute(execCtx, &input.ToolConfig, file)
-	if err != nil {
+	start := time.Now()
+	output, err := execute(execCtx, executor, &input.ToolConfig, file)
+	util.MetricExecutorDuration.ObserveSince(start)
+	if err != nil && stopCtx.Err() != nil {
+		return stoppedOutput(ctx)
+	} else if err != nil && ctx.Err() == nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		// 执行超时，executor返回了部分结果时一并上报
+		var result *object.Result
+		if output != nil {
+			result = output.Result
+		}
+		util.WarnContext(ctx, "analyze timeout, partial result: %t", result != nil)
+		return object.NewTimeoutOutput(err, result)
+	} else if err != nil {
 		errMsg := "Execute analysis failed: " + err.Error()
 		if ctx.Err() != nil {
 			errMsg = fmt.Sprintf("%s, ctx err[%s]", errMsg, ctx.Err().Error())
 		}
-		client.Failed(cancel, errors.New(errMsg))
-	} else {
-		client.Finish(cancel, output)
+		return failedOutput(ctx, errors.New(errMsg))
+	}
+	return output
+}
+
+func failedOutput(ctx context.Context, err error) *object.ToolOutput {
+	util.ErrorContext(ctx, "analyze failed %s", err)
+	return object.NewFailedOutput(err)
+}
+
+func stoppedOutput(ctx context.Context) *object.ToolOutput {
+	util.WarnContext(ctx, "analyze stopped")
+	return object.NewErrorOutput(errors.New("analysis stopped"), object.StatusStopped)
+}
+
+// withMaxTime 限制executor执行的最长时间，maxTime不大于0时不限制
+func withMaxTime(ctx context.Context, maxTime time.Duration) (context.Context, context.CancelFunc) {
+	if maxTime <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, maxTime)
+}
+
+// execute 执行分析，executor发生panic时返回*PanicError
+func execute(
+	ctx context.Context,
+	executor Executor,
+	config *object.ToolConfig,
+	file *os.File,
+) (output *object.ToolOutput, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+	output, err = executor.Execute(ctx, config, file)
+	if err == nil && output == nil {
+		err = errors.New("executor returned nil output")
 	}
+	return output, err
 }
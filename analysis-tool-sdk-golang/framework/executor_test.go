@@ -0,0 +1,345 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeStopped(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"))
+	defer analyst.Close()
+
+	started := make(chan struct{})
+	executor := &blockingExecutor{started: started}
+	args := newTestArguments(analyst.URL)
+	args.Parallel = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		analyze(ctx, executor, args)
+		close(done)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("executor not started")
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("analyze not stopped")
+	}
+
+	reports := analyst.Reports()
+	if len(reports) != 1 {
+		t.Fatalf("expect 1 report, got %d", len(reports))
+	}
+	if reports[0].SubTaskId != "task-1" || reports[0].ScanStatus != object.StatusStopped {
+		t.Fatalf("unexpected report: %s %s", reports[0].SubTaskId, reports[0].ScanStatus)
+	}
+}
+
+func TestAnalyzePanicRecovered(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"), newTestToolInput(t, "task-2"))
+	defer analyst.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, &panicExecutor{}, newTestArguments(analyst.URL))
+		close(done)
+	}()
+
+	reports := waitReports(t, analyst, 2)
+	cancel()
+	<-done
+
+	if reports[0].ScanStatus != object.StatusFailed ||
+		!strings.Contains(reports[0].ScanExecutorResult.Output.Err, "panic: task-1") {
+		t.Fatalf("unexpected report of task-1: %+v", reports[0].ScanExecutorResult.Output)
+	}
+	if reports[1].ScanStatus != object.StatusSuccess {
+		t.Fatalf("unexpected report of task-2: %s", reports[1].ScanStatus)
+	}
+}
+
+func TestAnalyzeTimeout(t *testing.T) {
+	input := newTestToolInput(t, "task-1")
+	input.ToolConfig.Args[0].Value = "100"
+	analyst := newFakeAnalyst(input)
+	defer analyst.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, &partialResultExecutor{}, newTestArguments(analyst.URL))
+		close(done)
+	}()
+
+	reports := waitReports(t, analyst, 1)
+	cancel()
+	<-done
+
+	output := reports[0].ScanExecutorResult.Output
+	if reports[0].ScanStatus != object.StatusTimeout || output.Status != object.StatusTimeout {
+		t.Fatalf("expect timeout, got %s", reports[0].ScanStatus)
+	}
+	if output.Result == nil || len(output.Result.SecurityResults) != 1 {
+		t.Fatalf("expect partial result, got %+v", output.Result)
+	}
+}
+
+func TestAnalyzeTaskWorkDir(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"))
+	defer analyst.Close()
+	args := newTestArguments(analyst.URL)
+	args.WorkDir = t.TempDir()
+
+	var workDir string
+	executor := ExecutorFunc(func(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		workDir = WorkDir(ctx)
+		if err := os.WriteFile(filepath.Join(workDir, "output.json"), []byte("{}"), 0644); err != nil {
+			return nil, err
+		}
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, executor, args)
+		close(done)
+	}()
+	reports := waitReports(t, analyst, 1)
+	cancel()
+	<-done
+
+	if reports[0].ScanStatus != object.StatusSuccess {
+		t.Fatalf("unexpected report: %+v", reports[0].ScanExecutorResult.Output)
+	}
+	if workDir != filepath.Join(args.WorkDir, "task-1") {
+		t.Fatalf("unexpected workdir: %s", workDir)
+	}
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Fatalf("expect workdir removed, got %v", err)
+	}
+}
+
+func TestAnalyzeCleanWorkRoot(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"), newTestToolInput(t, "task-2"))
+	defer analyst.Close()
+	args := newTestArguments(analyst.URL)
+	args.WorkDir = t.TempDir()
+
+	// 模拟直接写入工作空间根目录固定路径的执行器
+	resultPath := filepath.Join(args.WorkDir, "result.json")
+	executor := ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		if _, err := os.Stat(resultPath); err == nil {
+			return nil, errors.New("result of previous task exists")
+		}
+		if err := os.WriteFile(resultPath, []byte("{}"), 0644); err != nil {
+			return nil, err
+		}
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, executor, args)
+		close(done)
+	}()
+	reports := waitReports(t, analyst, 2)
+	cancel()
+	<-done
+
+	for _, report := range reports {
+		if report.ScanStatus != object.StatusSuccess {
+			t.Fatalf("unexpected report: %+v", report.ScanExecutorResult.Output)
+		}
+	}
+}
+
+func TestAnalyzeProgressHeartbeat(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"))
+	defer analyst.Close()
+	args := newTestArguments(analyst.URL)
+	args.Heartbeat = 1
+
+	executor := ExecutorFunc(func(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		util.ReportProgress(ctx, "scanning layers", 50, map[string]int64{"layers": 2})
+		deadline := time.Now().Add(10 * time.Second)
+		for len(analyst.Progresses()) == 0 && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, executor, args)
+		close(done)
+	}()
+	waitReports(t, analyst, 1)
+	cancel()
+	<-done
+
+	progresses := analyst.Progresses()
+	expected := `{"stage":"scanning layers","percent":50,"counters":{"layers":2}}`
+	if len(progresses) == 0 || progresses[0] != expected {
+		t.Fatalf("expected heartbeat progress %s, got %v", expected, progresses)
+	}
+}
+
+// waitReports 等待制品分析服务收到指定数量的上报结果
+func waitReports(t *testing.T, analyst *fakeAnalyst, count int) []api.ReportResultRequest {
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		if reports := analyst.Reports(); len(reports) >= count {
+			return reports
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("expect %d reports, got %d", count, len(analyst.Reports()))
+	return nil
+}
+
+// panicExecutor 第一次执行时panic
+type panicExecutor struct {
+	executed bool
+}
+
+func (e *panicExecutor) Execute(_ context.Context, _ *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+	if !e.executed {
+		e.executed = true
+		panic(filepath.Base(strings.TrimSuffix(file.Name(), ".txt")))
+	}
+	return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+}
+
+// partialResultExecutor 超时后返回部分结果
+type partialResultExecutor struct{}
+
+func (e *partialResultExecutor) Execute(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+	result := &object.Result{SecurityResults: []object.SecurityResult{{VulId: "CVE-2023-0001"}}}
+	<-ctx.Done()
+	return object.NewOutput(object.StatusSuccess, result), ctx.Err()
+}
+
+// blockingExecutor 一直执行直到ctx结束
+type blockingExecutor struct {
+	started chan struct{}
+}
+
+func (e *blockingExecutor) Execute(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+	close(e.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// fakeAnalyst 模拟制品分析服务
+type fakeAnalyst struct {
+	*httptest.Server
+	lock    sync.Mutex
+	inputs  []*object.ToolInput
+	reports []api.ReportResultRequest
+	// progresses 心跳请求中携带的进度
+	progresses []string
+}
+
+func newFakeAnalyst(inputs ...*object.ToolInput) *fakeAnalyst {
+	analyst := &fakeAnalyst{inputs: inputs}
+	analyst.Server = httptest.NewServer(http.HandlerFunc(analyst.handle))
+	return analyst
+}
+
+// Progresses 获取心跳请求中携带的进度
+func (a *fakeAnalyst) Progresses() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]string{}, a.progresses...)
+}
+
+// Reports 获取已上报的结果
+func (a *fakeAnalyst) Reports() []api.ReportResultRequest {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]api.ReportResultRequest{}, a.reports...)
+}
+
+func (a *fakeAnalyst) handle(w http.ResponseWriter, r *http.Request) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/api/analyst/api/temporary")
+	switch {
+	case path == "/scan/subtask/input":
+		res := api.Response[*object.ToolInput]{}
+		if len(a.inputs) > 0 {
+			res.Data = a.inputs[0]
+			a.inputs = a.inputs[1:]
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	case strings.HasSuffix(path, "/heartbeat"):
+		if progress := r.PostFormValue("progress"); progress != "" {
+			a.progresses = append(a.progresses, progress)
+		}
+	case strings.HasSuffix(path, "/status"):
+		_ = json.NewEncoder(w).Encode(api.Response[bool]{Data: true})
+	case path == "/scan/report":
+		req := api.ReportResultRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		a.reports = append(a.reports, req)
+		_ = json.NewEncoder(w).Encode(api.Response[bool]{Data: true})
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func newTestArguments(url string) *object.Arguments {
+	return &object.Arguments{
+		Url:              url,
+		Token:            "token",
+		ExecutionCluster: "test",
+		PullRetry:        -1,
+		KeepRunning:      true,
+		Parallel:         1,
+		GracePeriod:      10,
+		WorkDir:          filepath.Join(os.TempDir(), "bkrepo-analysis-workspace"),
+	}
+}
+
+func newTestToolInput(t *testing.T, taskId string) *object.ToolInput {
+	f := filepath.Join(t.TempDir(), taskId+".txt")
+	if err := os.WriteFile(f, []byte(taskId), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	return &object.ToolInput{
+		TaskId:   taskId,
+		FilePath: f,
+		ToolConfig: object.ToolConfig{
+			Args: []object.Argument{{Type: "NUMBER", Key: "maxTime", Value: "60000"}},
+		},
+	}
+}
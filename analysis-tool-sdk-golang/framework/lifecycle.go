@@ -0,0 +1,146 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Lifecycle 执行器可选实现的生命周期接口，用于在多个任务间复用耗时的初始化操作，例如解压漏洞库或启动辅助进程
+type Lifecycle interface {
+	// Init 执行第一个任务前调用，config为该任务的工具配置，ctx在进程退出时结束，不受任务maxTime限制
+	// 后续任务的工具配置发生变化时会等待正在执行的任务结束，先调用Close再使用新的配置调用Init
+	// Init发生panic时视为初始化失败，当前任务上报FAILED
+	Init(ctx context.Context, config *object.ToolConfig) error
+	// Close 进程退出或工具配置发生变化时调用
+	Close() error
+}
+
+// InitKeys 执行器可选实现的接口，返回会影响Init结果的配置键，仅这些配置变化时才会重新初始化
+// 未实现时除制品分析服务为每个任务单独设置的maxTime与packageType外，任意配置变化都会重新初始化
+type InitKeys interface {
+	InitKeys() []string
+}
+
+// taskArgKeys 制品分析服务为每个任务单独设置的配置
+var taskArgKeys = []string{"maxTime", util.ArgKeyPkgType}
+
+// managedExecutor 管理执行器的生命周期，多个worker共用同一个managedExecutor
+type managedExecutor struct {
+	// Executor 使用中间件包装后的执行器
+	Executor
+	// base 原始执行器，用于判断是否实现了Lifecycle等可选接口
+	base Executor
+	// lock 执行任务时持有读锁，重新初始化时持有写锁，保证不会在任务执行过程中重新初始化
+	lock        sync.RWMutex
+	initialized bool
+	config      *object.ToolConfig
+	// initializing 是否正在执行Init，用于就绪探针
+	initializing atomic.Bool
+}
+
+func newManagedExecutor(executor Executor, middlewares ...Middleware) *managedExecutor {
+	return &managedExecutor{Executor: Chain(executor, middlewares...), base: executor}
+}
+
+// prepare 在执行任务前调用，必要时初始化执行器，任务执行结束后需要调用返回的release函数
+// stopCtx为进程的退出上下文，初始化不应受单个任务的maxTime限制
+func (e *managedExecutor) prepare(stopCtx context.Context, config *object.ToolConfig) (release func(), err error) {
+	lifecycle, ok := implements[Lifecycle](e.base)
+	for {
+		e.lock.RLock()
+		if !ok || e.initialized && !e.configChanged(config) {
+			return e.lock.RUnlock, nil
+		}
+		e.lock.RUnlock()
+
+		e.lock.Lock()
+		err := e.init(stopCtx, lifecycle, config)
+		e.lock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// init 初始化执行器，需要持有写锁，Init发生panic时返回*PanicError
+func (e *managedExecutor) init(ctx context.Context, lifecycle Lifecycle, config *object.ToolConfig) (err error) {
+	if e.initialized && !e.configChanged(config) {
+		return nil
+	}
+	if e.initialized {
+		util.Info("tool config changed, close executor")
+		e.initialized = false
+		if err := lifecycle.Close(); err != nil {
+			util.Error("close executor failed: %s", err.Error())
+		}
+	}
+	util.Info("init executor")
+	e.initializing.Store(true)
+	defer e.initializing.Store(false)
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+	if err := lifecycle.Init(ctx, config); err != nil {
+		return err
+	}
+	c := *config
+	e.initialized = true
+	e.config = &c
+	util.Info("init executor success")
+	return nil
+}
+
+// close 关闭执行器
+func (e *managedExecutor) close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	lifecycle, ok := implements[Lifecycle](e.base)
+	if !ok || !e.initialized {
+		return
+	}
+	e.initialized = false
+	if err := lifecycle.Close(); err != nil {
+		util.Error("close executor failed: %s", err.Error())
+	} else {
+		util.Info("close executor success")
+	}
+}
+
+// configChanged 判断工具配置相对于初始化时使用的配置是否发生了变化
+func (e *managedExecutor) configChanged(config *object.ToolConfig) bool {
+	if keys, ok := implements[InitKeys](e.base); ok {
+		for _, key := range keys.InitKeys() {
+			if !slices.Equal(findArgs(e.config, key), findArgs(config, key)) {
+				return true
+			}
+		}
+		return false
+	}
+	return !slices.Equal(taskIndependentArgs(e.config), taskIndependentArgs(config))
+}
+
+func findArgs(config *object.ToolConfig, key string) []object.Argument {
+	var args []object.Argument
+	for _, arg := range config.Args {
+		if arg.Key == key {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func taskIndependentArgs(config *object.ToolConfig) []object.Argument {
+	var args []object.Argument
+	for _, arg := range config.Args {
+		if !slices.Contains(taskArgKeys, arg.Key) {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
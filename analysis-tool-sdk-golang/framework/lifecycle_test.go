@@ -0,0 +1,103 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLifecycle(t *testing.T) {
+	inputs := make([]*object.ToolInput, 3)
+	for i, taskId := range []string{"task-1", "task-2", "task-3"} {
+		inputs[i] = newTestToolInput(t, taskId)
+		dbUrl := "db-v1"
+		if i == 2 {
+			dbUrl = "db-v2"
+		}
+		inputs[i].ToolConfig.Args = append(inputs[i].ToolConfig.Args, object.Argument{
+			Type: "STRING", Key: "dbDownloadUrl", Value: dbUrl,
+		})
+	}
+	// 每个任务的maxTime不同时不需要重新初始化
+	inputs[1].ToolConfig.Args[0].Value = "120000"
+	analyst := newFakeAnalyst(inputs...)
+	defer analyst.Close()
+
+	executor := &lifecycleExecutor{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, executor, newTestArguments(analyst.URL))
+		close(done)
+	}()
+	waitReports(t, analyst, 3)
+	cancel()
+	<-done
+
+	if len(executor.inits) != 2 || executor.inits[0] != "db-v1" || executor.inits[1] != "db-v2" {
+		t.Fatalf("unexpected inits: %v", executor.inits)
+	}
+	if executor.closed != 2 {
+		t.Fatalf("expect closed 2 times, got %d", executor.closed)
+	}
+}
+
+func TestLifecyclePanicRecovered(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"), newTestToolInput(t, "task-2"))
+	defer analyst.Close()
+
+	executor := &panicInitExecutor{lifecycleExecutor: &lifecycleExecutor{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, executor, newTestArguments(analyst.URL))
+		close(done)
+	}()
+	reports := waitReports(t, analyst, 2)
+	cancel()
+	<-done
+
+	// Init发生panic时当前任务失败，worker继续执行下一个任务
+	if reports[0].ScanStatus != object.StatusFailed || reports[1].ScanStatus != object.StatusSuccess {
+		t.Fatalf("unexpected reports: %s, %s", reports[0].ScanStatus, reports[1].ScanStatus)
+	}
+	if !strings.Contains(reports[0].ScanExecutorResult.Output.Err, "panic: init boom") {
+		t.Fatalf("expect panic error, got %s", reports[0].ScanExecutorResult.Output.Err)
+	}
+}
+
+// panicInitExecutor 第一次Init时panic
+type panicInitExecutor struct {
+	*lifecycleExecutor
+	panicked bool
+}
+
+func (e *panicInitExecutor) Init(ctx context.Context, config *object.ToolConfig) error {
+	if !e.panicked {
+		e.panicked = true
+		panic("init boom")
+	}
+	return e.lifecycleExecutor.Init(ctx, config)
+}
+
+// lifecycleExecutor 记录Init与Close的调用
+type lifecycleExecutor struct {
+	inits  []string
+	closed int
+}
+
+func (e *lifecycleExecutor) Init(_ context.Context, config *object.ToolConfig) error {
+	e.inits = append(e.inits, config.GetStringArg("dbDownloadUrl"))
+	return nil
+}
+
+func (e *lifecycleExecutor) Close() error {
+	e.closed++
+	return nil
+}
+
+func (e *lifecycleExecutor) Execute(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+	return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+}
@@ -0,0 +1,55 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"net/http"
+	"time"
+)
+
+// metricsPath 输出Prometheus指标的路径
+const metricsPath = "/metrics"
+
+// serveMetrics 在addr上启动输出指标与探针的HTTP服务，返回停止服务的函数
+func serveMetrics(addr string) func() {
+	return serveHandler("metrics", addr, metricsHandler())
+}
+
+// serveProbes 在addr上启动仅提供探针的HTTP服务，返回停止服务的函数
+func serveProbes(addr string) func() {
+	return serveHandler("probe", addr, probeHandler())
+}
+
+func serveHandler(name string, addr string, handler http.Handler) func() {
+	httpServer := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		util.Info("%s listen on %s", name, addr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			util.Error("%s server failed: %s", name, err.Error())
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(ctx)
+	}
+}
+
+func metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, util.DefaultMetrics.Handler())
+	handleProbes(mux)
+	return mux
+}
+
+func probeHandler() http.Handler {
+	mux := http.NewServeMux()
+	handleProbes(mux)
+	return mux
+}
+
+func handleProbes(mux *http.ServeMux) {
+	mux.Handle(livenessPath, probes.handler(func() *probeResult { return probes.liveness(time.Now()) }))
+	mux.Handle(readinessPath, probes.handler(probes.readiness))
+}
@@ -0,0 +1,57 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeMetrics(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"), newTestToolInput(t, "task-2"))
+	defer analyst.Close()
+	args := newTestArguments(analyst.URL)
+	pulled := util.MetricTasksPulled.Value()
+	succeeded := util.MetricTasksFinished.Value(string(object.StatusSuccess))
+	failed := util.MetricTasksFinished.Value(string(object.StatusFailed))
+
+	executor := ExecutorFunc(func(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		if TaskFromContext(ctx).TaskId == "task-2" {
+			return nil, context.Canceled
+		}
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, executor, args)
+		close(done)
+	}()
+	waitReports(t, analyst, 2)
+	cancel()
+	<-done
+
+	if util.MetricTasksPulled.Value()-pulled != 2 ||
+		util.MetricTasksFinished.Value(string(object.StatusSuccess))-succeeded != 1 ||
+		util.MetricTasksFinished.Value(string(object.StatusFailed))-failed != 1 {
+		t.Errorf("unexpected task metrics")
+	}
+
+	res := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(res, httptest.NewRequest("GET", metricsPath, nil))
+	body, _ := io.ReadAll(res.Body)
+	for _, name := range []string{
+		"bkrepo_analysis_tasks_finished_total{",
+		"bkrepo_analysis_executor_duration_seconds_count",
+		"bkrepo_analysis_report_duration_seconds_bucket{",
+	} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("metric %s not found in:\n%s", name, body)
+		}
+	}
+}
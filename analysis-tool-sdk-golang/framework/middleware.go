@@ -0,0 +1,155 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExecutorFunc 函数形式的Executor
+type ExecutorFunc func(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error)
+
+// Execute 执行分析
+func (f ExecutorFunc) Execute(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+	return f(ctx, config, file)
+}
+
+// Middleware 执行器中间件，用于在Execute前后添加通用的处理逻辑
+type Middleware func(next Executor) Executor
+
+// Chain 使用中间件包装执行器，第一个中间件位于最外层
+func Chain(executor Executor, middlewares ...Middleware) Executor {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		executor = middlewares[i](executor)
+	}
+	return executor
+}
+
+// Timing 输出执行耗时
+func Timing() Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+			start := time.Now()
+			output, err := next.Execute(ctx, config, file)
+			// 执行器未使用SDK生成的待分析文件时file可能为nil
+			name := ""
+			if file != nil {
+				name = file.Name()
+			}
+			util.InfoContext(ctx, "execute %s took %v, success: %t", name, time.Since(start), err == nil)
+			return output, err
+		})
+	}
+}
+
+// Recover 执行器发生panic时返回*PanicError
+func Recover() Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(
+			ctx context.Context,
+			config *object.ToolConfig,
+			file *os.File,
+		) (output *object.ToolOutput, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					output, err = nil, NewPanicError(r)
+				}
+			}()
+			return next.Execute(ctx, config, file)
+		})
+	}
+}
+
+// Normalize 将结果中为nil的数组替换为空数组，避免制品分析服务解析结果失败
+func Normalize() Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+			output, err := next.Execute(ctx, config, file)
+			if output != nil {
+				output.Result = NormalizeResult(output.Result)
+			}
+			return output, err
+		})
+	}
+}
+
+// SeverityFilter 过滤掉低于minSeverity等级的漏洞，等级从高到低为critical、high、medium、low
+// minSeverity无法识别时返回错误，无法识别等级的漏洞会被保留
+func SeverityFilter(minSeverity string) (Middleware, error) {
+	minLevel := severityLevel(minSeverity)
+	if minLevel == 0 {
+		return nil, errors.New("unknown severity " + minSeverity + ", should be one of [critical,high,medium,low]")
+	}
+	return severityFilter(minLevel), nil
+}
+
+// MustSeverityFilter 与SeverityFilter相同，minSeverity无法识别时panic，用于minSeverity为常量的情况
+func MustSeverityFilter(minSeverity string) Middleware {
+	middleware, err := SeverityFilter(minSeverity)
+	if err != nil {
+		panic(err.Error())
+	}
+	return middleware
+}
+
+func severityFilter(minLevel int) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+			output, err := next.Execute(ctx, config, file)
+			if output == nil || output.Result == nil || output.Result.SecurityResults == nil {
+				return output, err
+			}
+			results := make([]object.SecurityResult, 0, len(output.Result.SecurityResults))
+			for _, r := range output.Result.SecurityResults {
+				if level := severityLevel(r.Severity); level == 0 || level >= minLevel {
+					results = append(results, r)
+				}
+			}
+			output.Result.SecurityResults = results
+			return output, err
+		})
+	}
+}
+
+// NormalizeResult 将结果中为nil的数组替换为空数组，result为nil时返回空结果
+func NormalizeResult(result *object.Result) *object.Result {
+	if result == nil {
+		result = new(object.Result)
+	}
+	if result.SecurityResults == nil {
+		result.SecurityResults = []object.SecurityResult{}
+	}
+	if result.LicenseResults == nil {
+		result.LicenseResults = []object.LicenseResult{}
+	}
+	if result.SensitiveResults == nil {
+		result.SensitiveResults = []object.SensitiveResult{}
+	}
+	for i := range result.SecurityResults {
+		r := &result.SecurityResults[i]
+		if r.PkgVersions == nil {
+			r.PkgVersions = []string{}
+		}
+		if r.References == nil {
+			r.References = []string{}
+		}
+	}
+	return result
+}
+
+// severityLevels 漏洞等级，数值越大等级越高
+var severityLevels = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// severityLevel 获取漏洞等级，无法识别时返回0
+func severityLevel(severity string) int {
+	return severityLevels[strings.ToLower(severity)]
+}
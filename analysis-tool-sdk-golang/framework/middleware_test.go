@@ -0,0 +1,78 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Executor) Executor {
+			return ExecutorFunc(func(ctx context.Context, config *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+				calls = append(calls, name)
+				return next.Execute(ctx, config, file)
+			})
+		}
+	}
+	executor := ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		calls = append(calls, "executor")
+		return object.NewOutput(object.StatusSuccess, &object.Result{
+			SecurityResults: []object.SecurityResult{
+				{VulId: "1", Severity: "LOW"}, {VulId: "2", Severity: "high"}, {VulId: "3", Severity: "UNKNOWN"},
+			},
+		}), nil
+	})
+
+	chain := Chain(executor, record("first"), record("second"), Normalize(), MustSeverityFilter("medium"))
+	output, err := chain.Execute(context.Background(), &object.ToolConfig{}, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(calls) != 3 || calls[0] != "first" || calls[1] != "second" || calls[2] != "executor" {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+	// 无法识别等级的漏洞会被保留
+	if len(output.Result.SecurityResults) != 2 || output.Result.SecurityResults[0].VulId != "2" ||
+		output.Result.SecurityResults[1].VulId != "3" {
+		t.Fatalf("unexpected security results: %+v", output.Result.SecurityResults)
+	}
+	if output.Result.LicenseResults == nil || output.Result.SecurityResults[0].References == nil {
+		t.Fatal("result not normalized")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	executor := ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		panic("boom")
+	})
+	_, err := Chain(executor, Recover()).Execute(context.Background(), &object.ToolConfig{}, nil)
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("expect panic error, got %v", err)
+	}
+}
+
+func TestSeverityFilterUnknown(t *testing.T) {
+	if _, err := SeverityFilter("urgent"); err == nil {
+		t.Fatal("expect error for unknown severity")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect panic for unknown severity")
+		}
+	}()
+	MustSeverityFilter("urgent")
+}
+
+func TestTimingNilFile(t *testing.T) {
+	executor := ExecutorFunc(func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		return object.NewOutput(object.StatusSuccess, nil), nil
+	})
+	if _, err := Chain(executor, Timing()).Execute(context.Background(), &object.ToolConfig{}, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+}
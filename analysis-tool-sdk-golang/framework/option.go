@@ -0,0 +1,23 @@
+package framework
+
+// Option Analyze的可选配置
+type Option func(o *options)
+
+type options struct {
+	middlewares []Middleware
+}
+
+// WithMiddlewares 使用中间件包装执行器，第一个中间件位于最外层
+func WithMiddlewares(middlewares ...Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,30 @@
+package framework
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// maxStackSize 上报的panic堆栈最大长度
+const maxStackSize = 4096
+
+// PanicError executor执行过程中发生了panic
+type PanicError struct {
+	// Value recover得到的值
+	Value any
+	// Stack 发生panic时的调用栈，超过maxStackSize的部分会被截断
+	Stack string
+}
+
+// NewPanicError 创建PanicError，需要在recover所在的defer函数中调用才能获取到正确的调用栈
+func NewPanicError(value any) *PanicError {
+	stack := debug.Stack()
+	if len(stack) > maxStackSize {
+		stack = append(stack[:maxStackSize], "\n..."...)
+	}
+	return &PanicError{Value: value, Stack: string(stack)}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
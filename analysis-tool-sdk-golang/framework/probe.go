@@ -0,0 +1,213 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// livenessPath 存活探针路径，失败时应重启进程
+	livenessPath = "/healthz"
+	// readinessPath 就绪探针路径
+	readinessPath = "/readyz"
+
+	// pullStaleTimeout 拉取任务时超过该时间没有成功请求制品分析服务时认为worker卡住
+	pullStaleTimeout = 10 * time.Minute
+	// taskOverdue 任务执行时间超过maxTime加上该时间时认为worker卡住，包括下载待分析文件的时间
+	taskOverdue = 30 * time.Minute
+	// unlimitedTaskOverdue 未限制maxTime的任务执行时间超过该时间时认为worker卡住
+	unlimitedTaskOverdue = 24 * time.Hour
+	// reportTimeout 上报结果超过该时间时认为worker卡住
+	reportTimeout = 10 * time.Minute
+)
+
+// workerPhase worker当前所处的阶段
+type workerPhase string
+
+const (
+	phaseIdle      workerPhase = "idle"
+	phasePulling   workerPhase = "pulling"
+	phaseRunning   workerPhase = "running"
+	phaseReporting workerPhase = "reporting"
+)
+
+// probeState 记录执行器与worker的运行状态，用于存活与就绪探针
+type probeState struct {
+	mu       sync.Mutex
+	stopCtx  context.Context
+	executor *managedExecutor
+	workers  []*workerState
+}
+
+// probes 当前进程的运行状态，analyze执行期间有效
+var probes = &probeState{}
+
+// workerState worker的运行状态
+type workerState struct {
+	id     int
+	client *api.BkRepoClient
+	mu     sync.Mutex
+	phase  workerPhase
+	since  time.Time
+	taskId string
+	// maxTime 正在执行的任务允许执行的最长时间，0表示不限制
+	maxTime time.Duration
+}
+
+// workerStatus worker运行状态快照
+type workerStatus struct {
+	Id       int         `json:"id"`
+	Phase    workerPhase `json:"phase"`
+	Since    time.Time   `json:"since"`
+	TaskId   string      `json:"taskId,omitempty"`
+	MaxTime  string      `json:"maxTime,omitempty"`
+	LastPull *time.Time  `json:"lastPull,omitempty"`
+	maxTime  time.Duration
+}
+
+// probeResult 探针结果
+type probeResult struct {
+	Status  string         `json:"status"`
+	Reasons []string       `json:"reasons,omitempty"`
+	Workers []workerStatus `json:"workers,omitempty"`
+}
+
+// start 开始记录运行状态，返回的函数用于结束记录
+func (p *probeState) start(stopCtx context.Context, executor *managedExecutor) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopCtx = stopCtx
+	p.executor = executor
+	p.workers = nil
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.stopCtx = nil
+		p.executor = nil
+		p.workers = nil
+	}
+}
+
+// addWorker 记录worker的运行状态
+func (p *probeState) addWorker(id int, client *api.BkRepoClient) *workerState {
+	state := &workerState{id: id, client: client, phase: phaseIdle, since: time.Now()}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.workers = append(p.workers, state)
+	return state
+}
+
+// liveness 存活检查，worker拉取任务、执行任务或上报结果卡住时失败
+func (p *probeState) liveness(now time.Time) *probeResult {
+	p.mu.Lock()
+	workers := p.workers
+	p.mu.Unlock()
+
+	result := &probeResult{}
+	for _, w := range workers {
+		status := w.status()
+		result.Workers = append(result.Workers, status)
+		if reason := status.stuck(now); reason != "" {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("worker %d %s", w.id, reason))
+		}
+	}
+	return result.done()
+}
+
+// readiness 就绪检查，未开始执行、正在退出或执行器正在初始化时失败
+func (p *probeState) readiness() *probeResult {
+	p.mu.Lock()
+	stopCtx, executor := p.stopCtx, p.executor
+	p.mu.Unlock()
+
+	result := &probeResult{}
+	switch {
+	case stopCtx == nil:
+		result.Reasons = append(result.Reasons, "analyze not started")
+	case stopCtx.Err() != nil:
+		result.Reasons = append(result.Reasons, "stopping")
+	case executor.initializing.Load():
+		result.Reasons = append(result.Reasons, "executor initializing")
+	}
+	return result.done()
+}
+
+func (r *probeResult) done() *probeResult {
+	r.Status = "UP"
+	if len(r.Reasons) > 0 {
+		r.Status = "DOWN"
+	}
+	return r
+}
+
+// handler 返回探针接口，检查失败时返回503
+func (p *probeState) handler(check func() *probeResult) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		result := check()
+		status := http.StatusOK
+		if result.Status != "UP" {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// enter 进入新的阶段
+func (s *workerState) enter(phase workerPhase, taskId string, maxTime time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phase = phase
+	s.since = time.Now()
+	s.taskId = taskId
+	s.maxTime = maxTime
+}
+
+func (s *workerState) status() workerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := workerStatus{Id: s.id, Phase: s.phase, Since: s.since, TaskId: s.taskId, maxTime: s.maxTime}
+	if s.maxTime > 0 {
+		status.MaxTime = s.maxTime.String()
+	}
+	if lastPull := s.client.LastPull(); !lastPull.IsZero() {
+		status.LastPull = &lastPull
+	}
+	return status
+}
+
+// stuck 判断worker是否卡住，返回卡住的原因
+func (s workerStatus) stuck(now time.Time) string {
+	age := now.Sub(s.Since)
+	switch s.Phase {
+	case phasePulling:
+		last := s.Since
+		if s.LastPull != nil && s.LastPull.After(last) {
+			last = *s.LastPull
+		}
+		if now.Sub(last) > pullStaleTimeout {
+			return fmt.Sprintf("has not pulled successfully since %s", last.Format(time.RFC3339))
+		}
+	case phaseRunning:
+		if s.maxTime > 0 && age > s.maxTime+taskOverdue {
+			return fmt.Sprintf("task %s running for %s, exceeds maxTime %s", s.TaskId, age.Round(time.Second), s.MaxTime)
+		}
+		if s.maxTime <= 0 && age > unlimitedTaskOverdue {
+			return fmt.Sprintf("task %s without maxTime running for %s", s.TaskId, age.Round(time.Second))
+		}
+	case phaseReporting:
+		if age > reportTimeout {
+			return fmt.Sprintf("reporting task %s for %s", s.TaskId, age.Round(time.Second))
+		}
+	}
+	return ""
+}
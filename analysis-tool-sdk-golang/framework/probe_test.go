@@ -0,0 +1,84 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbeLiveness(t *testing.T) {
+	p := &probeState{}
+	state := p.addWorker(0, api.NewClient(newTestArguments(""), t.TempDir()))
+	now := time.Now()
+
+	state.enter(phasePulling, "", 0)
+	if r := p.liveness(now); r.Status != "UP" {
+		t.Errorf("expect pulling worker alive, got %+v", r)
+	}
+	if r := p.liveness(now.Add(pullStaleTimeout + time.Minute)); r.Status != "DOWN" {
+		t.Errorf("expect stale pulling worker dead, got %+v", r)
+	}
+
+	state.enter(phaseRunning, "task-1", time.Hour)
+	if r := p.liveness(now.Add(time.Hour + taskOverdue/2)); r.Status != "UP" {
+		t.Errorf("expect running worker alive, got %+v", r)
+	}
+	if r := p.liveness(now.Add(time.Hour + taskOverdue + time.Minute)); r.Status != "DOWN" || len(r.Reasons) != 1 {
+		t.Errorf("expect overdue worker dead, got %+v", r)
+	}
+
+	state.enter(phaseRunning, "task-2", 0)
+	if r := p.liveness(now.Add(unlimitedTaskOverdue - time.Minute)); r.Status != "UP" {
+		t.Errorf("expect worker without maxTime alive, got %+v", r)
+	}
+	if r := p.liveness(now.Add(unlimitedTaskOverdue + time.Minute)); r.Status != "DOWN" {
+		t.Errorf("expect overdue worker without maxTime dead, got %+v", r)
+	}
+
+	state.enter(phaseReporting, "task-2", 0)
+	if r := p.liveness(now.Add(reportTimeout + time.Minute)); r.Status != "DOWN" {
+		t.Errorf("expect reporting worker dead, got %+v", r)
+	}
+}
+
+func TestProbeReadiness(t *testing.T) {
+	p := &probeState{}
+	handler := p.handler(p.readiness)
+	assertStatus := func(expected int) {
+		t.Helper()
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, readinessPath, nil))
+		if res.Code != expected {
+			t.Errorf("expect %d, got %d: %s", expected, res.Code, res.Body.String())
+		}
+	}
+	assertStatus(http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := newManagedExecutor(new(blockingExecutor))
+	stop := p.start(ctx, executor)
+	assertStatus(http.StatusOK)
+
+	executor.initializing.Store(true)
+	assertStatus(http.StatusServiceUnavailable)
+	executor.initializing.Store(false)
+
+	cancel()
+	assertStatus(http.StatusServiceUnavailable)
+	stop()
+	assertStatus(http.StatusServiceUnavailable)
+}
+
+func TestProbeHandler(t *testing.T) {
+	handler := probeHandler()
+	for path, expected := range map[string]int{livenessPath: http.StatusOK, metricsPath: http.StatusNotFound} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != expected {
+			t.Errorf("expect %d for %s, got %d", expected, path, res.Code)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// recycleCheckInterval 检查空闲时间与资源占用的间隔
+const recycleCheckInterval = 10 * time.Second
+
+// recycler keep-running模式下根据已执行任务数、空闲时间、内存与磁盘占用判断进程是否需要退出
+// 需要退出时结束ctx，worker不再拉取新任务，正在执行的任务不受影响
+type recycler struct {
+	args     *object.Arguments
+	workRoot string
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu         sync.Mutex
+	tasks      int
+	running    int
+	lastActive time.Time
+}
+
+func newRecycler(stopCtx context.Context, args *object.Arguments) *recycler {
+	ctx, cancel := context.WithCancel(stopCtx)
+	return &recycler{
+		args:       args,
+		workRoot:   api.WorkRoot(args),
+		ctx:        ctx,
+		cancel:     cancel,
+		lastActive: time.Now(),
+	}
+}
+
+// enabled 是否指定了任意退出条件
+func (r *recycler) enabled() bool {
+	return r.args.MaxTasks > 0 || r.args.MaxIdle > 0 || r.args.MaxRssMb > 0 || r.args.MinFreeDiskMb > 0
+}
+
+// watch 定期检查空闲时间与资源占用，直到需要退出或ctx结束
+func (r *recycler) watch() {
+	if !r.enabled() {
+		return
+	}
+	interval := recycleCheckInterval
+	if r.args.MaxIdle > 0 {
+		interval = min(interval, r.args.MaxIdle)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			idle := r.running == 0 && r.args.MaxIdle > 0 && time.Since(r.lastActive) >= r.args.MaxIdle
+			r.mu.Unlock()
+			if idle {
+				r.recycle(fmt.Sprintf("idle for %s", r.args.MaxIdle))
+				return
+			}
+			r.checkResources()
+		}
+	}
+}
+
+// begin 开始执行任务
+func (r *recycler) begin() {
+	r.mu.Lock()
+	r.tasks++
+	r.running++
+	r.lastActive = time.Now()
+	tasks := r.tasks
+	r.mu.Unlock()
+	if r.args.MaxTasks > 0 && tasks >= r.args.MaxTasks {
+		r.recycle(fmt.Sprintf("reached max tasks %d", r.args.MaxTasks))
+	}
+}
+
+// end 任务执行结束，工作空间已清理，检查资源占用
+func (r *recycler) end() {
+	r.mu.Lock()
+	r.running--
+	r.lastActive = time.Now()
+	r.mu.Unlock()
+	r.checkResources()
+}
+
+// checkResources 内存占用超过--max-rss-mb或磁盘可用空间低于--min-free-disk-mb时退出
+func (r *recycler) checkResources() {
+	if r.args.MaxRssMb > 0 {
+		if rss, err := util.ProcessRSS(); err != nil {
+			util.Warn("get process rss failed: %s", err.Error())
+		} else if rss > uint64(r.args.MaxRssMb)<<20 {
+			r.recycle(fmt.Sprintf("rss %dMB exceeds %dMB", rss>>20, r.args.MaxRssMb))
+		}
+	}
+	if r.args.MinFreeDiskMb > 0 {
+		if free, err := util.FreeDisk(existingDir(r.workRoot)); err != nil {
+			util.Warn("get free disk of %s failed: %s", r.workRoot, err.Error())
+		} else if free < uint64(r.args.MinFreeDiskMb)<<20 {
+			r.recycle(fmt.Sprintf("free disk %dMB less than %dMB", free>>20, r.args.MinFreeDiskMb))
+		}
+	}
+}
+
+// recycle 不再拉取新任务，等待正在执行的任务结束后退出
+func (r *recycler) recycle(reason string) {
+	if r.ctx.Err() != nil {
+		return
+	}
+	util.Info("worker recycling: %s, exit after running tasks finished", reason)
+	r.cancel()
+}
+
+// recycling 是否需要退出
+func (r *recycler) recycling() bool {
+	return r.ctx.Err() != nil
+}
+
+// existingDir 获取path或其最近的已存在的上级目录，工作空间根目录在执行第一个任务前可能不存在
+func existingDir(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
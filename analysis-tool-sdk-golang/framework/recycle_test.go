@@ -0,0 +1,82 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeMaxTasks(t *testing.T) {
+	analyst := newFakeAnalyst(
+		newTestToolInput(t, "task-1"), newTestToolInput(t, "task-2"), newTestToolInput(t, "task-3"),
+	)
+	defer analyst.Close()
+	args := newTestArguments(analyst.URL)
+	args.MaxTasks = 2
+
+	err := analyzeWithTimeout(t, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports := analyst.Reports(); len(reports) != 2 {
+		t.Fatalf("expect 2 reports, got %d", len(reports))
+	}
+}
+
+func TestAnalyzeMaxIdle(t *testing.T) {
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-1"))
+	defer analyst.Close()
+	args := newTestArguments(analyst.URL)
+	args.MaxIdle = 500 * time.Millisecond
+
+	err := analyzeWithTimeout(t, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports := analyst.Reports(); len(reports) != 1 {
+		t.Fatalf("expect 1 report, got %d", len(reports))
+	}
+}
+
+func TestRecyclerFreeDisk(t *testing.T) {
+	args := newTestArguments("")
+	args.WorkDir = t.TempDir()
+	args.MinFreeDiskMb = 1 << 40
+	r := newRecycler(context.Background(), args)
+	defer r.cancel()
+	if _, err := util.FreeDisk(args.WorkDir); errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("free disk unsupported")
+	}
+	r.begin()
+	if r.recycling() {
+		t.Fatal("unexpected recycling before task finished")
+	}
+	r.end()
+	if !r.recycling() {
+		t.Fatal("expect recycling when free disk is low")
+	}
+}
+
+// analyzeWithTimeout 执行分析并等待满足退出条件后返回
+func analyzeWithTimeout(t *testing.T, args *object.Arguments) error {
+	executor := ExecutorFunc(func(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- analyze(ctx, executor, args)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(20 * time.Second):
+		t.Fatal("analyze not recycled")
+		return nil
+	}
+}
@@ -0,0 +1,344 @@
+package framework
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus HTTP服务模式下扫描任务的状态
+type JobStatus string
+
+const (
+	// JobPending 等待执行
+	JobPending JobStatus = "PENDING"
+	// JobExecuting 执行中
+	JobExecuting JobStatus = "EXECUTING"
+	// JobFinished 执行结束，执行结果见Job.Output
+	JobFinished JobStatus = "FINISHED"
+)
+
+// jobsPath 扫描任务接口路径
+const jobsPath = "/jobs"
+
+// maxFinishedJobs 最多保留的已结束任务数量，超过时删除最早结束的任务
+const maxFinishedJobs = 1000
+
+// maxUploadMemory 上传文件时最多使用的内存，超过的部分会写入临时文件
+const maxUploadMemory = 32 << 20
+
+// maxPendingJobs 最多等待执行的任务数量，超过时拒绝提交新任务
+const maxPendingJobs = 100
+
+// Job HTTP服务模式下的扫描任务
+type Job struct {
+	Id         string             `json:"id"`
+	Status     JobStatus          `json:"status"`
+	CreateTime time.Time          `json:"createTime"`
+	FinishTime *time.Time         `json:"finishTime,omitempty"`
+	Output     *object.ToolOutput `json:"output,omitempty"`
+	input      *object.ToolInput
+}
+
+// server 通过REST API接收扫描任务，复用Executor执行扫描
+//
+//	POST /jobs               提交任务，请求体为ToolInput，或使用multipart/form-data上传file字段及可选的input字段
+//	GET  /jobs/{id}          查询任务状态
+//	GET  /jobs/{id}/output   获取任务的ToolOutput，任务未结束时返回202
+type server struct {
+	ctx      context.Context
+	executor *managedExecutor
+	args     *object.Arguments
+	workDir  string
+	// queue 等待执行的任务，由args.Parallel个goroutine依次执行
+	queue    chan *Job
+	lock     sync.Mutex
+	jobs     map[string]*Job
+	finished []string
+	wg       sync.WaitGroup
+}
+
+func newServer(ctx context.Context, executor *managedExecutor, args *object.Arguments, workDir string) *server {
+	parallel := args.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	s := &server{
+		ctx:      ctx,
+		executor: executor,
+		args:     args,
+		workDir:  workDir,
+		queue:    make(chan *Job, maxPendingJobs),
+		jobs:     make(map[string]*Job),
+	}
+	s.wg.Add(parallel)
+	for i := 0; i < parallel; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// serve 启动HTTP服务直到ctx结束，结束后等待正在执行的任务中止
+func serve(ctx context.Context, executor *managedExecutor, args *object.Arguments) error {
+	s := newServer(ctx, executor, args, filepath.Join(api.WorkRoot(args), "jobs"))
+	httpServer := &http.Server{Addr: args.Listen, Handler: s.handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		util.Info("listen on %s", args.Listen)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(args.GracePeriod)*time.Second)
+		err = httpServer.Shutdown(shutdownCtx)
+		cancel()
+	}
+	s.wg.Wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(jobsPath, s.handleSubmit)
+	mux.HandleFunc(jobsPath+"/", s.handleJob)
+	return mux
+}
+
+// handleSubmit 提交任务
+func (s *server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.ctx.Err() != nil {
+		writeError(w, http.StatusServiceUnavailable, "server is stopping")
+		return
+	}
+
+	if s.args.MaxRequestMb > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(s.args.MaxRequestMb)<<20)
+	}
+	job := &Job{Id: newJobId(), Status: JobPending, CreateTime: time.Now()}
+	input, err := s.readInput(r, job.Id)
+	if err != nil {
+		s.cleanJobDir(job)
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	if input.TaskId == "" {
+		input.TaskId = job.Id
+	}
+	job.input = input
+
+	s.lock.Lock()
+	s.jobs[job.Id] = job
+	s.lock.Unlock()
+	select {
+	case s.queue <- job:
+	default:
+		s.lock.Lock()
+		delete(s.jobs, job.Id)
+		s.lock.Unlock()
+		s.cleanJobDir(job)
+		writeError(w, http.StatusServiceUnavailable, "too many pending jobs")
+		return
+	}
+	util.Info("job %s submitted", job.Id)
+	writeJson(w, http.StatusAccepted, s.view(job))
+}
+
+// handleJob 查询任务状态或输出
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, jobsPath+"/"), "/")
+	s.lock.Lock()
+	job, ok := s.jobs[id]
+	s.lock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "job "+id+" not found")
+		return
+	}
+
+	view := s.view(job)
+	switch sub {
+	case "":
+		writeJson(w, http.StatusOK, view)
+	case "output":
+		if view.Status != JobFinished {
+			writeJson(w, http.StatusAccepted, view)
+		} else {
+			writeJson(w, http.StatusOK, view.Output)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// readInput 从请求中读取ToolInput，上传文件时会保存到任务工作空间并设置为ToolInput.FilePath
+func (s *server) readInput(r *http.Request, jobId string) (*object.ToolInput, error) {
+	input := new(object.ToolInput)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			return nil, err
+		}
+		// 不允许通过请求读取服务所在机器上的任意文件，本地文件需要上传
+		if input.FilePath != "" {
+			return nil, errors.New("filePath is not allowed, upload the file instead")
+		}
+		if len(input.FileUrls) == 0 {
+			return nil, errors.New("fileUrls required")
+		}
+		return input, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, err
+	}
+	if inputJson := r.FormValue("input"); inputJson != "" {
+		if err := json.Unmarshal([]byte(inputJson), input); err != nil {
+			return nil, err
+		}
+	}
+	src, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	dir := filepath.Join(s.workDir, jobId, "upload")
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return nil, err
+	}
+	dst, err := os.Create(filepath.Join(dir, filepath.Base(header.Filename)))
+	if err != nil {
+		return nil, err
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		return nil, err
+	}
+	input.FilePath = dst.Name()
+	return input, nil
+}
+
+// work 依次执行队列中的任务直到ctx结束，结束时未执行的任务均标记为已中止
+func (s *server) work() {
+	defer s.wg.Done()
+	for {
+		select {
+		case job := <-s.queue:
+			s.run(job)
+		case <-s.ctx.Done():
+			for {
+				select {
+				case job := <-s.queue:
+					s.run(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run 执行任务，ctx已结束时不再执行
+func (s *server) run(job *Job) {
+	defer s.cleanJobDir(job)
+
+	var output *object.ToolOutput
+	if s.ctx.Err() != nil {
+		output = stoppedOutput(s.ctx)
+	} else {
+		s.setStatus(job, JobExecuting)
+		client := api.NewClient(s.args, filepath.Join(s.workDir, job.Id))
+		client.ToolInput = job.input
+		ctx, cancel := context.WithCancel(s.ctx)
+		clearToolInput := shareToolInput(s.args, job.input)
+		output = runTask(ctx, s.ctx, s.executor, client)
+		clearToolInput()
+		cancel()
+	}
+	output.TaskId = job.input.TaskId
+	s.finish(job, output)
+	util.Info("job %s finished, status: %s", job.Id, output.Status)
+}
+
+// cleanJobDir 清理任务工作空间，包括上传的文件
+func (s *server) cleanJobDir(job *Job) {
+	if err := util.CleanDir(filepath.Join(s.workDir, job.Id)); err != nil {
+		util.Error("clean job %s workdir failed: %s", job.Id, err.Error())
+	}
+}
+
+func (s *server) setStatus(job *Job, status JobStatus) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	job.Status = status
+}
+
+func (s *server) finish(job *Job, output *object.ToolOutput) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	job.Status = JobFinished
+	job.FinishTime = &now
+	job.Output = output
+	s.finished = append(s.finished, job.Id)
+	if len(s.finished) > maxFinishedJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// view 获取任务当前状态的副本
+func (s *server) view(job *Job) Job {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return *job
+}
+
+func newJobId() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		util.Error("write response failed: %s", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, map[string]string{"message": message})
+}
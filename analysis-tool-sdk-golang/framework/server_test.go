@@ -0,0 +1,197 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	executor := newManagedExecutor(ExecutorFunc(
+		func(_ context.Context, _ *object.ToolConfig, file *os.File) (*object.ToolOutput, error) {
+			content, err := os.ReadFile(file.Name())
+			if err != nil {
+				return nil, err
+			}
+			return object.NewOutput(object.StatusSuccess, &object.Result{
+				SensitiveResults: []object.SensitiveResult{{Content: string(content)}},
+			}), nil
+		},
+	))
+	s := newServer(ctx, executor, &object.Arguments{Parallel: 2}, t.TempDir())
+	httpServer := httptest.NewServer(s.handler())
+	defer httpServer.Close()
+
+	// 上传文件提交任务
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("input", `{"toolConfig":{"args":[{"type":"STRING","key":"packageType","value":"GENERIC"}]}}`)
+	part, _ := writer.CreateFormFile("file", "app.jar")
+	_, _ = part.Write([]byte("uploaded"))
+	_ = writer.Close()
+	job := submitJob(t, httpServer.URL, writer.FormDataContentType(), body)
+	output := waitJobOutput(t, httpServer.URL, job.Id)
+	if output.Status != object.StatusSuccess || output.TaskId != job.Id ||
+		output.Result.SensitiveResults[0].Content != "uploaded" {
+		t.Fatalf("unexpected output: %+v", output)
+	}
+
+	// 提交ToolInput，从fileUrls下载待分析文件
+	fileServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("downloaded"))
+	}))
+	defer fileServer.Close()
+	input := &object.ToolInput{
+		TaskId:   "task-1",
+		FileUrls: []object.FileUrl{{Url: fileServer.URL + "/app.jar", Name: "app.jar", Sha256: sha256Hex("downloaded")}},
+	}
+	inputJson, _ := json.Marshal(input)
+	job = submitJob(t, httpServer.URL, "application/json", bytes.NewReader(inputJson))
+	output = waitJobOutput(t, httpServer.URL, job.Id)
+	if output.Status != object.StatusSuccess || output.TaskId != "task-1" ||
+		output.Result.SensitiveResults[0].Content != "downloaded" {
+		t.Fatalf("unexpected output: %+v", output)
+	}
+
+	// 不允许读取服务所在机器上的文件
+	inputJson, _ = json.Marshal(newTestToolInput(t, "task-2"))
+	res, err := http.Post(httpServer.URL+"/jobs", "application/json", bytes.NewReader(inputJson))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expect 400 for filePath, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(httpServer.URL + "/jobs/not-exists")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expect 404, got %d", res.StatusCode)
+	}
+}
+
+func TestServerQueueFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := newManagedExecutor(&blockingExecutor{started: make(chan struct{})})
+	s := newServer(ctx, executor, &object.Arguments{Parallel: 1}, t.TempDir())
+	httpServer := httptest.NewServer(s.handler())
+	defer httpServer.Close()
+	fileServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("downloaded"))
+	}))
+	defer fileServer.Close()
+
+	inputJson, _ := json.Marshal(&object.ToolInput{FileUrls: []object.FileUrl{
+		{Url: fileServer.URL + "/a", Name: "a", Sha256: sha256Hex("downloaded")},
+	}})
+	var rejected bool
+	for i := 0; i < maxPendingJobs+2 && !rejected; i++ {
+		res, err := http.Post(httpServer.URL+"/jobs", "application/json", bytes.NewReader(inputJson))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_ = res.Body.Close()
+		rejected = res.StatusCode == http.StatusServiceUnavailable
+	}
+	if !rejected {
+		t.Fatal("expect 503 when queue is full")
+	}
+
+	// 退出时等待中的任务标记为已中止
+	cancel()
+	s.wg.Wait()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, job := range s.jobs {
+		if job.Status != JobFinished {
+			t.Fatalf("job %s not finished after stop: %s", job.Id, job.Status)
+		}
+	}
+}
+
+func TestServerMaxRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	executor := newManagedExecutor(ExecutorFunc(
+		func(_ context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+			return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+		},
+	))
+	s := newServer(ctx, executor, &object.Arguments{Parallel: 1, MaxRequestMb: 1}, t.TempDir())
+	httpServer := httptest.NewServer(s.handler())
+	defer httpServer.Close()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "app.jar")
+	_, _ = part.Write(bytes.Repeat([]byte("a"), 2<<20))
+	_ = writer.Close()
+	res, err := http.Post(httpServer.URL+"/jobs", writer.FormDataContentType(), body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", res.StatusCode)
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func submitJob(t *testing.T, url string, contentType string, body io.Reader) *Job {
+	res, err := http.Post(url+"/jobs", contentType, body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("submit job failed: %s", res.Status)
+	}
+	job := new(Job)
+	if err := json.NewDecoder(res.Body).Decode(job); err != nil {
+		t.Fatal(err.Error())
+	}
+	return job
+}
+
+func waitJobOutput(t *testing.T, url string, id string) *object.ToolOutput {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		res, err := http.Get(url + "/jobs/" + id + "/output")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if res.StatusCode == http.StatusOK {
+			output := new(object.ToolOutput)
+			err := json.NewDecoder(res.Body).Decode(output)
+			_ = res.Body.Close()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			return output
+		}
+		_ = res.Body.Close()
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("job %s not finished", id)
+	return nil
+}
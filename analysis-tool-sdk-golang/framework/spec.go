@@ -0,0 +1,131 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Describer 执行器可选实现的接口，返回工具的描述信息与参数定义
+// 实现后可以通过--print-tool-json生成tool.json，并且框架会在执行任务前校验包类型与必填参数
+type Describer interface {
+	Describe() *ToolSpec
+}
+
+// ToolSpec 工具描述信息
+type ToolSpec struct {
+	// Name 工具名
+	Name string
+	// Image 工具镜像
+	Image string
+	// Cmd 启动镜像用的命令
+	Cmd string
+	// Version 工具版本
+	Version string
+	// Description 工具描述
+	Description string
+	// Args 工具参数
+	Args []ArgSpec
+	// SupportFileNameExt 支持扫描的文件名后缀
+	SupportFileNameExt []string
+	// SupportPackageTypes 支持扫描的包类型，为空时不校验包类型
+	SupportPackageTypes []string
+	// SupportScanTypes 支持的扫描类型，取值范围[SECURITY,SENSITIVE,LICENSE]
+	SupportScanTypes []string
+}
+
+// ArgSpec 工具参数定义
+type ArgSpec struct {
+	// Type 参数类型，取值范围[STRING,NUMBER,BOOLEAN,STRING_LIST,MAP,JSON,DURATION]
+	Type string
+	// Key 参数键
+	Key string
+	// Default 参数默认值，会作为tool.json中的参数值
+	Default string
+	// Des 参数描述
+	Des string
+	// Required 是否必填，任务的工具配置中缺少该参数时任务失败
+	Required bool
+}
+
+// ToolInfo 转换为tool.json
+func (s *ToolSpec) ToolInfo() *object.ToolInfo {
+	args := make([]object.Argument, len(s.Args))
+	for i, arg := range s.Args {
+		args[i] = object.Argument{Type: arg.Type, Key: arg.Key, Value: arg.Default, Des: arg.Des}
+	}
+	return &object.ToolInfo{
+		Name:                s.Name,
+		Image:               s.Image,
+		Cmd:                 s.Cmd,
+		Version:             s.Version,
+		Args:                args,
+		Type:                object.ToolTypeStandard,
+		Description:         s.Description,
+		SupportFileNameExt:  nonNil(s.SupportFileNameExt),
+		SupportPackageTypes: nonNil(s.SupportPackageTypes),
+		SupportScanTypes:    nonNil(s.SupportScanTypes),
+	}
+}
+
+// Validate 校验任务的包类型与必填参数，返回所有校验失败的原因
+func (s *ToolSpec) Validate(config *object.ToolConfig) error {
+	var errs []error
+	pkgType := config.GetStringArg(util.ArgKeyPkgType)
+	if pkgType != "" && len(s.SupportPackageTypes) > 0 && !slices.Contains(s.SupportPackageTypes, pkgType) {
+		errs = append(errs, fmt.Errorf(
+			"unsupported package type %s, supported: %s", pkgType, strings.Join(s.SupportPackageTypes, ","),
+		))
+	}
+	for _, arg := range s.Args {
+		if arg.Required && !hasArg(config, arg.Key) {
+			errs = append(errs, errors.New("missing required arg "+arg.Key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PrintToolJson 输出执行器对应的tool.json
+func PrintToolJson(w io.Writer, executor Executor) error {
+	describer, ok := implements[Describer](executor)
+	if !ok {
+		return errors.New("executor does not implement Describer")
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(describer.Describe().ToolInfo())
+}
+
+// hasArg 工具配置中是否存在值不为空的参数，不区分参数类型
+func hasArg(config *object.ToolConfig, key string) bool {
+	return slices.ContainsFunc(findArgs(config, key), func(arg object.Argument) bool { return arg.Value != "" })
+}
+
+// Validator 执行器可选实现的接口，在下载待分析文件与执行分析前校验工具配置，校验失败时任务以FAILED状态结束
+type Validator interface {
+	Validate(config *object.ToolConfig) error
+}
+
+// validate 校验任务的工具配置，执行器实现了Describer或Validator时生效
+func (e *managedExecutor) validate(config *object.ToolConfig) error {
+	var errs []error
+	if describer, ok := implements[Describer](e.base); ok {
+		errs = append(errs, describer.Describe().Validate(config))
+	}
+	if validator, ok := implements[Validator](e.base); ok {
+		errs = append(errs, validator.Validate(config))
+	}
+	return errors.Join(errs...)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,68 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrintToolJson(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := PrintToolJson(buf, AdaptTaskExecutor(&describedExecutor{})); err != nil {
+		t.Fatal(err.Error())
+	}
+	info := new(object.ToolInfo)
+	if err := json.Unmarshal(buf.Bytes(), info); err != nil {
+		t.Fatal(err.Error())
+	}
+	if info.Name != "scanner" || info.Type != object.ToolTypeStandard || len(info.Args) != 2 ||
+		info.Args[1].Value != "false" || info.SupportScanTypes == nil {
+		t.Fatalf("unexpected tool.json: %s", buf.String())
+	}
+
+	if err := PrintToolJson(buf, &panicExecutor{}); err == nil {
+		t.Fatal("expect error for executor without Describer")
+	}
+}
+
+func TestToolSpecValidate(t *testing.T) {
+	spec := (&describedExecutor{}).Describe()
+	config := &object.ToolConfig{Args: []object.Argument{
+		{Type: "STRING", Key: "packageType", Value: "NPM"},
+	}}
+	err := spec.Validate(config)
+	if err == nil || !strings.Contains(err.Error(), "unsupported package type NPM") ||
+		!strings.Contains(err.Error(), "missing required arg dbUrl") {
+		t.Fatalf("unexpected validate result: %v", err)
+	}
+
+	config.Args = []object.Argument{
+		{Type: "STRING", Key: "packageType", Value: "MAVEN"},
+		{Type: "STRING", Key: "dbUrl", Value: "http://db"},
+	}
+	if err := spec.Validate(config); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+// describedExecutor 声明了工具描述信息的执行器
+type describedExecutor struct{}
+
+func (e *describedExecutor) Describe() *ToolSpec {
+	return &ToolSpec{
+		Name: "scanner",
+		Args: []ArgSpec{
+			{Type: object.ArgTypeString, Key: "dbUrl", Required: true},
+			{Type: object.ArgTypeBoolean, Key: "scanLicense", Default: "false"},
+		},
+		SupportPackageTypes: []string{"MAVEN", "GENERIC"},
+	}
+}
+
+func (e *describedExecutor) ExecuteTask(_ context.Context, _ *Task, _ *os.File) (*object.ToolOutput, error) {
+	return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+}
@@ -0,0 +1,140 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Task 执行器执行的任务上下文
+type Task struct {
+	// TaskId 子任务id
+	TaskId string
+	// Sha256 待分析制品的sha256
+	Sha256 string
+	// FileName 待分析制品的原始文件名
+	FileName string
+	// PackageType 待分析制品的包类型
+	PackageType string
+	// FileUrls 待分析制品的下载地址
+	FileUrls []object.FileUrl
+	// Config 工具配置
+	Config *object.ToolConfig
+	// WorkDir 任务工作空间，任务结束后会被删除
+	WorkDir string
+	// Logger 带有taskId属性的日志
+	Logger *slog.Logger
+}
+
+// TaskExecutor 使用任务上下文执行分析的执行器，通过AdaptTaskExecutor转换为Executor后传给Analyze
+type TaskExecutor interface {
+	// ExecuteTask 执行分析，file为待分析的制品，返回值与Executor.Execute相同
+	ExecuteTask(ctx context.Context, task *Task, file *os.File) (*object.ToolOutput, error)
+}
+
+// TaskExecutorFunc 函数形式的TaskExecutor
+type TaskExecutorFunc func(ctx context.Context, task *Task, file *os.File) (*object.ToolOutput, error)
+
+// ExecuteTask 执行分析
+func (f TaskExecutorFunc) ExecuteTask(ctx context.Context, task *Task, file *os.File) (*object.ToolOutput, error) {
+	return f(ctx, task, file)
+}
+
+// AdaptTaskExecutor 将TaskExecutor转换为Executor，可以与中间件、CompositeExecutor一起使用
+// 执行器实现的Lifecycle与InitKeys接口仍然生效
+func AdaptTaskExecutor(executor TaskExecutor) Executor {
+	return &taskExecutorAdapter{executor: executor}
+}
+
+type taskExecutorAdapter struct {
+	executor TaskExecutor
+}
+
+// Execute 从ctx中获取任务上下文执行分析，ctx不是由框架传入时仅包含工具配置
+func (a *taskExecutorAdapter) Execute(
+	ctx context.Context,
+	config *object.ToolConfig,
+	file *os.File,
+) (*object.ToolOutput, error) {
+	return a.executor.ExecuteTask(ctx, taskOrDefault(ctx, config), file)
+}
+
+func (a *taskExecutorAdapter) unwrap() any {
+	return a.executor
+}
+
+// unwrapper 由框架转换得到的执行器，用于获取被转换的执行器
+type unwrapper interface {
+	unwrap() any
+}
+
+// implements 判断执行器或被转换的执行器是否实现了Lifecycle等可选接口T，返回最外层的实现
+func implements[T any](executor any) (T, bool) {
+	for executor != nil {
+		if t, ok := executor.(T); ok {
+			return t, true
+		}
+		u, ok := executor.(unwrapper)
+		if !ok {
+			break
+		}
+		executor = u.unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+type taskKey struct{}
+
+// TaskFromContext 获取框架传给执行器的任务上下文，ctx不是由框架传入时返回nil
+func TaskFromContext(ctx context.Context) *Task {
+	task, _ := ctx.Value(taskKey{}).(*Task)
+	return task
+}
+
+// WorkDir 获取当前任务的工作空间，执行器的临时文件与输出文件应写入该目录，任务结束后框架会删除该目录
+// ctx不是由框架传入时返回util.WorkDir
+func WorkDir(ctx context.Context) string {
+	if task := TaskFromContext(ctx); task != nil {
+		return task.WorkDir
+	}
+	return util.WorkDir
+}
+
+// taskOrDefault 获取任务上下文，ctx不是由框架传入时返回仅包含工具配置的任务上下文
+func taskOrDefault(ctx context.Context, config *object.ToolConfig) *Task {
+	if task := TaskFromContext(ctx); task != nil {
+		return task
+	}
+	return &Task{Config: config, WorkDir: util.WorkDir, Logger: util.LoggerFromContext(ctx)}
+}
+
+// withTask 将任务上下文与任务日志保存到ctx中
+func withTask(ctx context.Context, task *Task) context.Context {
+	return util.WithLogger(context.WithValue(ctx, taskKey{}, task), task.Logger)
+}
+
+// newTask 根据工具输入创建任务上下文
+func newTask(input *object.ToolInput, workDir string) *Task {
+	task := &Task{
+		TaskId:      input.TaskId,
+		Sha256:      input.Sha256,
+		PackageType: input.ToolConfig.GetStringArg(util.ArgKeyPkgType),
+		FileUrls:    input.FileUrls,
+		Config:      &input.ToolConfig,
+		WorkDir:     workDir,
+		Logger:      util.Logger().With("taskId", input.TaskId),
+	}
+	if len(input.FileUrls) > 0 {
+		task.FileName = input.FileUrls[0].Name
+		if task.Sha256 == "" {
+			task.Sha256 = input.FileUrls[0].Sha256
+		}
+	} else if input.FilePath != "" {
+		task.FileName = filepath.Base(input.FilePath)
+	}
+	return task
+}
@@ -0,0 +1,59 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"testing"
+)
+
+func TestAdaptTaskExecutor(t *testing.T) {
+	input := newTestToolInput(t, "task-1")
+	input.Sha256 = "sha256"
+	input.ToolConfig.Args = append(input.ToolConfig.Args, object.Argument{
+		Type: "STRING", Key: "packageType", Value: "MAVEN",
+	})
+	analyst := newFakeAnalyst(input)
+	defer analyst.Close()
+
+	executor := &taskExecutor{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, AdaptTaskExecutor(executor), newTestArguments(analyst.URL))
+		close(done)
+	}()
+	waitReports(t, analyst, 1)
+	cancel()
+	<-done
+
+	task := executor.task
+	if task == nil || task.TaskId != "task-1" || task.Sha256 != "sha256" || task.FileName != "task-1.txt" ||
+		task.PackageType != "MAVEN" || task.WorkDir == "" || task.Logger == nil {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+	if executor.inits != 1 {
+		t.Fatalf("expect init 1 time, got %d", executor.inits)
+	}
+}
+
+// taskExecutor 记录执行时的任务上下文
+type taskExecutor struct {
+	task  *Task
+	inits int
+}
+
+func (e *taskExecutor) Init(_ context.Context, _ *object.ToolConfig) error {
+	e.inits++
+	return nil
+}
+
+func (e *taskExecutor) Close() error {
+	return nil
+}
+
+func (e *taskExecutor) ExecuteTask(_ context.Context, task *Task, _ *os.File) (*object.ToolOutput, error) {
+	e.task = task
+	task.Logger.Info("execute task")
+	return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+}
@@ -0,0 +1,161 @@
+package framework
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"github.com/hashicorp/go-retryablehttp"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// taskLogName 任务工作空间中的任务执行日志文件名
+const taskLogName = "task.log"
+
+// maxAttachedTaskLogSize 附加到上报结果中的日志最大原始大小，超过时只附加日志末尾部分
+const maxAttachedTaskLogSize = 4 << 20
+
+// taskLog 将任务日志同时保存到任务工作空间中，任务结束后压缩并附加到结果中或上传
+type taskLog struct {
+	file   *os.File
+	taskId string
+	// stopCapture 停止保存不带ctx的日志
+	stopCapture func()
+}
+
+// newTaskLog 创建任务日志文件并替换任务上下文中的日志，args.TaskLog为空时返回nil
+// 同一时间只执行一个任务时，不带ctx输出的日志（例如拉取、下载过程中的日志）也会保存到任务日志中
+func newTaskLog(args *object.Arguments, task *Task) (*taskLog, error) {
+	if args.TaskLog == "" {
+		return nil, nil
+	}
+	file, err := os.Create(filepath.Join(task.WorkDir, taskLogName))
+	if err != nil {
+		return nil, err
+	}
+	handler, err := util.NewHandler(file, orDefault(args.LogFormat, util.LogFormatText), orDefault(args.LogLevel, "info"))
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	task.Logger = slog.New(util.NewTeeHandler(util.Logger().Handler(), handler)).With("taskId", task.TaskId)
+	l := &taskLog{file: file, taskId: task.TaskId, stopCapture: func() {}}
+	if singleTask(args) {
+		l.stopCapture = util.CaptureLogs(handler.WithAttrs([]slog.Attr{slog.String("taskId", task.TaskId)}))
+	}
+	return l, nil
+}
+
+// singleTask 同一时间是否只会执行一个任务
+func singleTask(args *object.Arguments) bool {
+	if args.Serve() || args.Batch() {
+		return args.Parallel <= 1
+	}
+	return args.WorkerCount() == 1
+}
+
+// finish 关闭日志文件，根据args.TaskLog将日志附加到output中或上传
+func (l *taskLog) finish(ctx context.Context, args *object.Arguments, output *object.ToolOutput) {
+	l.stopCapture()
+	if err := l.file.Close(); err != nil {
+		util.ErrorContext(ctx, "close task log failed: %s", err.Error())
+		return
+	}
+	var err error
+	switch args.TaskLog {
+	case object.TaskLogAttach:
+		output.Log, err = l.attach()
+	case object.TaskLogUpload:
+		// 任务被中止时仍然上传日志
+		output.Log, err = l.upload(context.WithoutCancel(ctx), args)
+	}
+	if err != nil {
+		util.ErrorContext(ctx, "%s task log failed: %s", args.TaskLog, err.Error())
+	}
+}
+
+// attach 压缩日志末尾部分并编码为base64
+func (l *taskLog) attach() (*object.TaskLog, error) {
+	content, size, truncated, err := compressLog(l.file.Name(), maxAttachedTaskLogSize)
+	if err != nil {
+		return nil, err
+	}
+	return &object.TaskLog{
+		Name:      taskLogName + ".gz",
+		Size:      size,
+		Truncated: truncated,
+		Content:   base64.StdEncoding.EncodeToString(content),
+	}, nil
+}
+
+// upload 压缩完整日志并PUT到args.TaskLogUrl
+func (l *taskLog) upload(ctx context.Context, args *object.Arguments) (*object.TaskLog, error) {
+	content, size, _, err := compressLog(l.file.Name(), -1)
+	if err != nil {
+		return nil, err
+	}
+	reqUrl := strings.ReplaceAll(args.TaskLogUrl, "{taskId}", url.PathEscape(l.taskId))
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPut, reqUrl, content)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	for k, v := range args.TaskLogHeaders {
+		req.Header.Set(k, v)
+	}
+	res, err := util.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer util.DrainBody(res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("upload task log failed, status: %s", res.Status)
+	}
+	return &object.TaskLog{Name: taskLogName + ".gz", Size: size, Url: reqUrl}, nil
+}
+
+// compressLog 使用gzip压缩日志，maxSize大于0且日志超过maxSize时只压缩末尾的maxSize字节
+func compressLog(path string, maxSize int64) (content []byte, size int64, truncated bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	size = info.Size()
+	if maxSize > 0 && size > maxSize {
+		if _, err := f.Seek(size-maxSize, io.SeekStart); err != nil {
+			return nil, 0, false, err
+		}
+		truncated = true
+	}
+
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := io.Copy(w, f); err != nil {
+		return nil, 0, false, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0, false, err
+	}
+	return buf.Bytes(), size, truncated, nil
+}
+
+func orDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package framework
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTaskLog(t *testing.T) {
+	var lock sync.Mutex
+	uploaded := make(map[string][]byte)
+	logServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		uploaded[r.URL.Path] = content
+		lock.Unlock()
+	}))
+	defer logServer.Close()
+
+	executor := ExecutorFunc(func(ctx context.Context, _ *object.ToolConfig, _ *os.File) (*object.ToolOutput, error) {
+		if err := util.ExecAndLog(ctx, "sh", []string{"-c", "echo scanning"}, ""); err != nil {
+			return nil, err
+		}
+		util.Info("log without ctx")
+		return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+	})
+	for _, mode := range []string{object.TaskLogAttach, object.TaskLogUpload} {
+		analyst := newFakeAnalyst(newTestToolInput(t, "task-"+mode))
+		args := newTestArguments(analyst.URL)
+		args.TaskLog = mode
+		args.TaskLogUrl = logServer.URL + "/logs/{taskId}.log.gz"
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			_ = analyze(ctx, executor, args)
+			close(done)
+		}()
+		reports := waitReports(t, analyst, 1)
+		cancel()
+		<-done
+		analyst.Close()
+
+		taskLog := reports[0].ScanExecutorResult.Output.Log
+		if taskLog == nil {
+			t.Fatalf("%s: expect task log", mode)
+		}
+		var content []byte
+		if mode == object.TaskLogAttach {
+			content, _ = base64.StdEncoding.DecodeString(taskLog.Content)
+		} else {
+			if taskLog.Url != logServer.URL+"/logs/task-upload.log.gz" {
+				t.Fatalf("unexpected log url: %s", taskLog.Url)
+			}
+			lock.Lock()
+			content = uploaded["/logs/task-upload.log.gz"]
+			lock.Unlock()
+		}
+		log := gunzip(t, content)
+		if !strings.Contains(log, "msg=scanning taskId=task-"+mode+" stream=stdout") {
+			t.Fatalf("%s: unexpected task log: %s", mode, log)
+		}
+		// 只执行一个任务时不带ctx的日志也会被保存
+		if !strings.Contains(log, `msg="log without ctx" taskId=task-`+mode) {
+			t.Fatalf("%s: expect logs without ctx in task log: %s", mode, log)
+		}
+	}
+}
+
+func gunzip(t *testing.T, content []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	log, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return string(log)
+}
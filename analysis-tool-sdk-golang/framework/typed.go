@@ -0,0 +1,63 @@
+package framework
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+)
+
+// TypedExecutor 使用绑定到结构体的工具配置执行分析的执行器，T为声明了arg标签的结构体，参考object.ToolConfig.Bind
+type TypedExecutor[T any] interface {
+	ExecuteTyped(ctx context.Context, task *Task, config *T, file *os.File) (*object.ToolOutput, error)
+}
+
+// TypedExecutorFunc 函数形式的TypedExecutor
+type TypedExecutorFunc[T any] func(ctx context.Context, task *Task, config *T, file *os.File) (*object.ToolOutput, error)
+
+// ExecuteTyped 执行分析
+func (f TypedExecutorFunc[T]) ExecuteTyped(
+	ctx context.Context,
+	task *Task,
+	config *T,
+	file *os.File,
+) (*object.ToolOutput, error) {
+	return f(ctx, task, config, file)
+}
+
+// AdaptTypedExecutor 将TypedExecutor转换为Executor
+// 工具配置会在下载待分析文件前绑定并校验，失败时任务以FAILED状态结束，错误信息包含所有不合法的参数
+func AdaptTypedExecutor[T any](executor TypedExecutor[T]) Executor {
+	return &typedExecutorAdapter[T]{executor: executor}
+}
+
+type typedExecutorAdapter[T any] struct {
+	executor TypedExecutor[T]
+}
+
+// Validate 校验工具配置能否绑定到T，绑定成功后executor实现了Validator时继续执行自定义校验
+func (a *typedExecutorAdapter[T]) Validate(config *object.ToolConfig) error {
+	if err := config.Bind(new(T)); err != nil {
+		return err
+	}
+	if validator, ok := implements[Validator](a.executor); ok {
+		return validator.Validate(config)
+	}
+	return nil
+}
+
+// Execute 绑定工具配置后执行分析
+func (a *typedExecutorAdapter[T]) Execute(
+	ctx context.Context,
+	config *object.ToolConfig,
+	file *os.File,
+) (*object.ToolOutput, error) {
+	typedConfig := new(T)
+	if err := config.Bind(typedConfig); err != nil {
+		return nil, err
+	}
+	return a.executor.ExecuteTyped(ctx, taskOrDefault(ctx, config), typedConfig, file)
+}
+
+func (a *typedExecutorAdapter[T]) unwrap() any {
+	return a.executor
+}
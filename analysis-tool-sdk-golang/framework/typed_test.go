@@ -0,0 +1,82 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"os"
+	"strings"
+	"testing"
+)
+
+type typedConfig struct {
+	MaxTime int64  `arg:"maxTime,required"`
+	DbUrl   string `arg:"dbUrl,required"`
+}
+
+func TestAdaptTypedExecutor(t *testing.T) {
+	valid := newTestToolInput(t, "task-1")
+	valid.ToolConfig.Args = append(valid.ToolConfig.Args, object.Argument{Type: "STRING", Key: "dbUrl", Value: "db"})
+	analyst := newFakeAnalyst(newTestToolInput(t, "task-2"), valid)
+	defer analyst.Close()
+
+	var configs []*typedConfig
+	executor := TypedExecutorFunc[typedConfig](
+		func(_ context.Context, _ *Task, config *typedConfig, _ *os.File) (*object.ToolOutput, error) {
+			configs = append(configs, config)
+			return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+		},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = analyze(ctx, AdaptTypedExecutor[typedConfig](executor), newTestArguments(analyst.URL))
+		close(done)
+	}()
+	reports := waitReports(t, analyst, 2)
+	cancel()
+	<-done
+
+	if reports[0].ScanStatus != object.StatusFailed ||
+		!strings.Contains(reports[0].ScanExecutorResult.Output.Err, "arg dbUrl: required") {
+		t.Fatalf("unexpected report of task-2: %+v", reports[0].ScanExecutorResult.Output)
+	}
+	if reports[1].ScanStatus != object.StatusSuccess || len(configs) != 1 ||
+		configs[0].MaxTime != 60000 || configs[0].DbUrl != "db" {
+		t.Fatalf("unexpected configs: %+v", configs)
+	}
+}
+
+// validatedTypedExecutor 实现了自定义校验的TypedExecutor
+type validatedTypedExecutor struct{}
+
+func (e *validatedTypedExecutor) ExecuteTyped(
+	_ context.Context,
+	_ *Task,
+	_ *typedConfig,
+	_ *os.File,
+) (*object.ToolOutput, error) {
+	return object.NewOutput(object.StatusSuccess, new(object.Result)), nil
+}
+
+func (e *validatedTypedExecutor) Validate(config *object.ToolConfig) error {
+	if !strings.HasPrefix(config.GetStringArg("dbUrl"), "http") {
+		return errors.New("dbUrl must be http url")
+	}
+	return nil
+}
+
+func TestAdaptTypedExecutorValidator(t *testing.T) {
+	executor := newManagedExecutor(AdaptTypedExecutor[typedConfig](new(validatedTypedExecutor)))
+	config := &object.ToolConfig{Args: []object.Argument{
+		{Type: "NUMBER", Key: "maxTime", Value: "1000"},
+		{Type: "STRING", Key: "dbUrl", Value: "db"},
+	}}
+	if err := executor.validate(config); err == nil || !strings.Contains(err.Error(), "dbUrl must be http url") {
+		t.Fatalf("expect custom validate error, got %v", err)
+	}
+	config.Args[1].Value = "http://db"
+	if err := executor.validate(config); err != nil {
+		t.Fatalf("unexpected validate error: %s", err.Error())
+	}
+}
@@ -0,0 +1,115 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/api"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/util"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// failureWait 任务执行出错后等待多久再拉取下一个任务
+const failureWait = 5 * time.Second
+
+// worker 循环拉取并执行子任务，每个worker持有独立的客户端状态
+type worker struct {
+	id       int
+	executor *managedExecutor
+	client   *api.BkRepoClient
+	// state 运行状态，用于存活探针
+	state *workerState
+	// recycler 多个worker共用，需要退出时不再拉取新任务
+	recycler *recycler
+	// cleanRoot 每个任务结束后是否清理工作空间根目录，只有一个worker时才能清理
+	cleanRoot bool
+}
+
+func newWorker(id int, executor *managedExecutor, client *api.BkRepoClient, recycler *recycler) *worker {
+	return &worker{
+		id:       id,
+		executor: executor,
+		client:   client,
+		state:    probes.addWorker(id, client),
+		recycler: recycler,
+	}
+}
+
+// run 执行分析，keep-running模式下会一直拉取任务执行直到ctx结束或满足退出条件
+// keep-running模式下任务执行出错时仅输出日志并继续执行下一个任务，否则返回错误
+func (w *worker) run(ctx context.Context) error {
+	args := w.client.Args
+	for {
+		util.Info("worker %d start analyze", w.id)
+		err := w.doAnalyze(ctx)
+		util.Info("worker %d keep running %t", w.id, args.ShouldKeepRunning())
+		w.state.enter(phaseIdle, "", 0)
+		if !args.ShouldKeepRunning() {
+			return err
+		}
+		if err != nil {
+			util.Error("worker %d analyze failed: %s", w.id, err.Error())
+			// 避免服务异常时频繁请求
+			select {
+			case <-w.recycler.ctx.Done():
+			case <-time.After(failureWait):
+			}
+		}
+		if w.cleanRoot {
+			w.cleanWorkRoot()
+		}
+		if ctx.Err() != nil {
+			util.Info("worker %d stopped", w.id)
+			return nil
+		}
+		if w.recycler.recycling() {
+			util.Info("worker %d recycled", w.id)
+			return nil
+		}
+	}
+}
+
+// doAnalyze 拉取并执行一个任务，任务开始或上报结果失败时返回error
+// 需要退出时停止拉取任务，已拉取到的任务会执行完并上报结果
+func (w *worker) doAnalyze(stopCtx context.Context) error {
+	ctx, cancel := context.WithCancel(stopCtx)
+	defer cancel()
+	w.state.enter(phaseReporting, "", 0)
+	w.client.RedeliverReports()
+	w.state.enter(phasePulling, "", 0)
+	input, err := w.client.StartWithPullContext(ctx, w.recycler.ctx, cancel)
+	if err != nil {
+		return fmt.Errorf("start analyze failed: %w", err)
+	}
+	if input == nil || input.TaskId == "" {
+		util.Info("no subtask found, exit")
+		return nil
+	}
+	w.recycler.begin()
+	defer w.recycler.end()
+	w.state.enter(phaseRunning, input.TaskId, input.MaxTime())
+	output := runTask(ctx, stopCtx, w.executor, w.client)
+	w.state.enter(phaseReporting, input.TaskId, 0)
+	return w.client.Finish(cancel, output)
+}
+
+// cleanWorkRoot 删除工作空间根目录下的所有文件
+// 部分执行器直接将结果写入util.WorkDir下的固定路径，不清理时下一个任务可能读取到上一个任务的结果
+func (w *worker) cleanWorkRoot() {
+	root := api.WorkRoot(w.client.Args)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			util.Error("worker %d read workdir %s failed: %s", w.id, root, err.Error())
+		}
+		return
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			util.Error("worker %d clean workdir %s failed: %s", w.id, root, err.Error())
+			return
+		}
+	}
+	util.Info("worker %d clean workdir %s success", w.id, root)
+}
@@ -5,6 +5,7 @@ go 1.21.0
 require (
 	github.com/hashicorp/go-retryablehttp v0.7.4
 	golang.org/x/sync v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
@@ -3,6 +3,10 @@ package object
 import (
 	"flag"
 	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"time"
 )
 
 // Arguments 输入参数
@@ -16,43 +20,198 @@ type Arguments struct {
 	OutputFilePath   string
 	KeepRunning      bool
 	Heartbeat        int
+	Parallel         int
+	GracePeriod      int
+	OutboxDir        string
+	Listen           string
+	MaxRequestMb     int
+	FilePath         string
+	PackageType      string
+	ToolArgs         ToolArgFlags
+	MaxTime          time.Duration
+	BatchInput       string
+	OutputDir        string
+	Reporters        ReporterFlags
+	WebhookUrl       string
+	WebhookHeaders   HeaderFlags
+	WorkDir          string
+	PrintToolJson    bool
+	ConfigFile       string
+	TokenFile        string
+	LogFormat        string
+	LogLevel         string
+	TaskLog          string
+	TaskLogUrl       string
+	TaskLogHeaders   HeaderFlags
+	MetricsListen    string
+	ProbeListen      string
+	MaxTasks         int
+	MaxIdle          time.Duration
+	MaxRssMb         int
+	MinFreeDiskMb    int
+	PullInterval     time.Duration
+	PullMaxInterval  time.Duration
+	PullMaxErrors    int
+	PullWait         time.Duration
 }
 
+const (
+	// TaskLogAttach 任务执行日志附加到上报结果中
+	TaskLogAttach = "attach"
+	// TaskLogUpload 任务执行日志上传到指定地址
+	TaskLogUpload = "upload"
+)
+
 var args *Arguments
 
 func newArguments() *Arguments {
 	args = new(Arguments)
-	flag.StringVar(&args.Url, "url", "", "制品库地址")
-	flag.StringVar(&args.Token, "token", "", "制品库临时令牌")
-	flag.StringVar(&args.TaskId, "task-id", "", "扫描任务Id")
-	flag.StringVar(&args.ExecutionCluster, "execution-cluster", "", "所在扫描执行集群名")
-	flag.IntVar(&args.PullRetry, "pull-retry", -1, "拉取模式下拉取任务的次数，-1表示一直拉取直到拉取到任务")
-	flag.BoolVar(&args.KeepRunning, "keep-running", true, "是否一直运行，仅在拉取任务模式下生效")
-	flag.StringVar(&args.InputFilePath, "input", "", "输入文件路径")
-	flag.StringVar(&args.OutputFilePath, "output", "", "输出文件路径")
-	flag.IntVar(&args.Heartbeat, "heartbeat", 0, "任务心跳上报间隔，0表示不上报")
+	defineFlags(flag.CommandLine, args)
 	flag.Parse()
+	if err := loadSources(flag.CommandLine, args); err != nil {
+		panic(err.Error())
+	}
+	if args.PrintToolJson {
+		return args
+	}
 
-	fmt.Printf(
-		"url: %s, token: %s, taskId: %s, executionCluster: %s, pull-retry: %d, keep-running: %t, heartbeat: %d"+
-			"inputFilePath: %s, outputFilePath: %s\n",
-		args.Url,
-		args.Token,
+	// 输出到标准错误，避免--file模式下影响输出到标准输出的扫描结果
+	fmt.Fprintf(
+		os.Stderr,
+		"url: %s, token: %s, taskId: %s, executionCluster: %s, pull-retry: %d, keep-running: %t, heartbeat: %d, "+
+			"parallel: %d, grace-period: %d, outbox-dir: %s, listen: %s, max-request-mb: %d, inputFilePath: %s, "+
+			"outputFilePath: %s, file: %s, package-type: %s, args: %s, max-time: %s, batch: %s, output-dir: %s, reporters: %s, "+
+			"webhook-url: %s, webhook-headers: %s, work-dir: %s, config: %s, token-file: %s, log-format: %s, "+
+			"log-level: %s, task-log: %s, task-log-url: %s, task-log-headers: %s, metrics-listen: %s, probe-listen: %s, "+
+			"max-tasks: %d, max-idle: %s, max-rss-mb: %d, min-free-disk-mb: %d, pull-interval: %s, "+
+			"pull-max-interval: %s, pull-max-errors: %d, pull-wait: %s\n",
+		redactUrl(args.Url),
+		redact(args.Token),
 		args.TaskId,
 		args.ExecutionCluster,
 		args.PullRetry,
 		args.KeepRunning,
 		args.Heartbeat,
+		args.Parallel,
+		args.GracePeriod,
+		args.OutboxDir,
+		args.Listen,
+		args.MaxRequestMb,
 		args.InputFilePath,
 		args.OutputFilePath,
+		args.FilePath,
+		args.PackageType,
+		args.ToolArgs.String(),
+		args.MaxTime,
+		args.BatchInput,
+		args.OutputDir,
+		args.Reporters.String(),
+		args.WebhookUrl,
+		args.WebhookHeaders.String(),
+		args.WorkDir,
+		args.ConfigFile,
+		args.TokenFile,
+		args.LogFormat,
+		args.LogLevel,
+		args.TaskLog,
+		args.TaskLogUrl,
+		args.TaskLogHeaders.String(),
+		args.MetricsListen,
+		args.ProbeListen,
+		args.MaxTasks,
+		args.MaxIdle,
+		args.MaxRssMb,
+		args.MinFreeDiskMb,
+		args.PullInterval,
+		args.PullMaxInterval,
+		args.PullMaxErrors,
+		args.PullWait,
 	)
-	if (args.Offline() || args.Online()) == false {
+	if (args.Offline() || args.Online() || args.Serve() || args.ScanFile() || args.Batch()) == false {
 		panic("缺少必要输入参数")
 	}
+	// 批量扫描时每个输入的输出文件路径由框架指定
+	if slices.Contains(args.Reporters, ReporterFile) && args.OutputFilePath == "" && !args.Batch() {
+		panic("file上报方式缺少--output参数")
+	}
+	if slices.Contains(args.Reporters, ReporterWebhook) && args.WebhookUrl == "" {
+		panic("webhook上报方式缺少--webhook-url参数")
+	}
+	if args.TaskLog != "" && args.TaskLog != TaskLogAttach && args.TaskLog != TaskLogUpload {
+		panic("不支持的--task-log参数: " + args.TaskLog)
+	}
+	if args.TaskLog == TaskLogUpload && args.TaskLogUrl == "" {
+		panic("上传任务执行日志缺少--task-log-url参数")
+	}
+	if args.PullWait != 0 && args.PullWait < time.Second {
+		panic("--pull-wait参数不能小于1s")
+	}
 
 	return args
 }
 
+// Secrets 获取需要在日志中隐藏的敏感信息
+func (arg *Arguments) Secrets() []string {
+	secrets := []string{arg.Token}
+	for _, v := range arg.WebhookHeaders {
+		secrets = append(secrets, v)
+	}
+	for _, v := range arg.TaskLogHeaders {
+		secrets = append(secrets, v)
+	}
+	if u, err := url.Parse(arg.Url); err == nil && u.User != nil {
+		password, _ := u.User.Password()
+		secrets = append(secrets, password)
+	}
+	return secrets
+}
+
+// defineFlags 定义命令行参数
+func defineFlags(fs *flag.FlagSet, args *Arguments) {
+	fs.StringVar(&args.Url, "url", "", "制品库地址")
+	fs.StringVar(&args.Token, "token", "", "制品库临时令牌")
+	fs.StringVar(&args.TokenFile, "token-file", "", "从文件中读取制品库临时令牌，未指定--token时生效，例如挂载的Kubernetes Secret")
+	fs.StringVar(&args.ConfigFile, "config", "", "yaml或json格式的配置文件，键为参数名，优先级低于命令行与BKREPO_前缀的环境变量")
+	fs.StringVar(&args.TaskId, "task-id", "", "扫描任务Id")
+	fs.StringVar(&args.ExecutionCluster, "execution-cluster", "", "所在扫描执行集群名")
+	fs.IntVar(&args.PullRetry, "pull-retry", -1, "拉取模式下拉取任务的次数，-1表示一直拉取直到拉取到任务")
+	fs.DurationVar(&args.PullInterval, "pull-interval", 5*time.Second, "未拉取到任务或拉取出错后的初始等待时间，连续未拉取到任务时按指数增加")
+	fs.DurationVar(&args.PullMaxInterval, "pull-max-interval", time.Minute, "拉取任务的最长等待时间，实际等待时间会在其一半到全部之间随机")
+	fs.IntVar(&args.PullMaxErrors, "pull-max-errors", 10, "拉取任务连续出错的最大次数，超过时返回错误，-1表示不限制")
+	fs.DurationVar(&args.PullWait, "pull-wait", 0, "拉取任务时请求制品分析服务长轮询等待的时间，例如30s，不能小于1s，0表示不使用长轮询")
+	fs.BoolVar(&args.KeepRunning, "keep-running", true, "是否一直运行，仅在拉取任务模式下生效")
+	fs.StringVar(&args.InputFilePath, "input", "", "输入文件路径")
+	fs.StringVar(&args.OutputFilePath, "output", "", "输出文件路径")
+	fs.IntVar(&args.Heartbeat, "heartbeat", 0, "任务心跳上报间隔，0表示不上报")
+	fs.IntVar(&args.Parallel, "parallel", 1, "同时执行的任务数量，在拉取任务且keep-running模式、HTTP服务模式（--listen）与批量离线扫描模式（--batch）下生效")
+	fs.IntVar(&args.GracePeriod, "grace-period", 30, "收到退出信号后等待当前任务上报STOPPED的最长时间，单位为秒")
+	fs.StringVar(&args.OutboxDir, "outbox-dir", "/bkrepo/outbox", "上报失败的结果保存目录，会在下次拉取任务前重新上报，为空时不保存")
+	fs.StringVar(&args.Listen, "listen", "", "HTTP服务监听地址，例如:8080，设置后以HTTP服务模式运行，通过REST API提交扫描任务，服务没有鉴权，只应监听内网地址")
+	fs.IntVar(&args.MaxRequestMb, "max-request-mb", 1024, "HTTP服务模式下提交任务的请求体（包括上传的文件）最大MB，超过时返回413，0表示不限制")
+	fs.StringVar(&args.FilePath, "file", "", "直接扫描指定文件，不需要编写input.json，未指定--output时结果输出到标准输出")
+	fs.StringVar(&args.PackageType, "package-type", "GENERIC", "--file指定的文件的包类型")
+	fs.Var(&args.ToolArgs, "arg", "--file模式下的工具参数，格式为[TYPE:]key=value，可以指定多次，未指定TYPE时为STRING")
+	fs.DurationVar(&args.MaxTime, "max-time", 0, "--file模式下允许执行的最长时间，例如10m，0表示不限制")
+	fs.StringVar(&args.BatchInput, "batch", "", "批量离线扫描，值为目录时扫描目录下所有input.json，也可以是匹配输入文件的glob")
+	fs.StringVar(&args.OutputDir, "output-dir", "", "批量离线扫描时output.json与summary.json的输出目录，为空时输出到输入文件所在目录")
+	fs.Var(&args.Reporters, "reporters", "结果上报方式，可选default,file,analyst,stdout,webhook，多个使用逗号分隔，默认为default")
+	fs.StringVar(&args.WebhookUrl, "webhook-url", "", "webhook上报方式POST工具输出的地址")
+	fs.Var(&args.WebhookHeaders, "webhook-headers", "webhook上报方式的请求头，格式为k1:v1,k2:v2")
+	fs.StringVar(&args.WorkDir, "work-dir", "", "工作空间根目录，每个任务使用其中以任务id命名的子目录，为空时使用/bkrepo/workspace")
+	fs.StringVar(&args.LogFormat, "log-format", "text", "日志格式，可选text,json")
+	fs.StringVar(&args.LogLevel, "log-level", "info", "日志级别，可选debug,info,warn,error")
+	fs.StringVar(&args.TaskLog, "task-log", "", "保存任务执行日志，attach表示附加到上报结果中，upload表示上传到--task-log-url，为空时不保存")
+	fs.StringVar(&args.TaskLogUrl, "task-log-url", "", "任务执行日志的上传地址，{taskId}会被替换为任务id，使用PUT请求上传gzip压缩后的日志")
+	fs.Var(&args.TaskLogHeaders, "task-log-headers", "上传任务执行日志的请求头，格式为k1:v1,k2:v2")
+	fs.StringVar(&args.MetricsListen, "metrics-listen", "", "指标与探针监听地址，例如:9100，设置后通过/metrics输出Prometheus指标，通过/healthz与/readyz提供存活与就绪探针，为空时不监听")
+	fs.StringVar(&args.ProbeListen, "probe-listen", "", "存活与就绪探针单独的监听地址，例如:8080，不需要输出指标时使用，为空时仅在--metrics-listen上提供探针")
+	fs.IntVar(&args.MaxTasks, "max-tasks", 0, "keep-running模式下执行指定数量的任务后退出，0表示不限制")
+	fs.DurationVar(&args.MaxIdle, "max-idle", 0, "keep-running模式下超过指定时间没有执行任务时退出，例如30m，0表示不限制")
+	fs.IntVar(&args.MaxRssMb, "max-rss-mb", 0, "keep-running模式下进程占用内存超过指定MB时，执行完当前任务后退出，0表示不限制")
+	fs.IntVar(&args.MinFreeDiskMb, "min-free-disk-mb", 0, "keep-running模式下工作空间所在磁盘可用空间低于指定MB时，执行完当前任务后退出，0表示不限制")
+	fs.BoolVar(&args.PrintToolJson, "print-tool-json", false, "输出执行器声明的tool.json后退出")
+}
+
 // GetArgs 获取输入参数
 func GetArgs() *Arguments {
 	if args == nil {
@@ -67,12 +226,55 @@ func (arg *Arguments) Offline() bool {
 	return arg.InputFilePath != "" && arg.OutputFilePath != ""
 }
 
+// ScanFile 直接扫描--file指定的文件
+func (arg *Arguments) ScanFile() bool {
+	return arg.FilePath != "" && arg.InputFilePath == ""
+}
+
+// Batch 批量离线扫描
+func (arg *Arguments) Batch() bool {
+	return arg.BatchInput != ""
+}
+
 // Online 在线扫描
 func (arg *Arguments) Online() bool {
 	return arg.Url != "" && arg.Token != "" && (arg.TaskId != "" || arg.ExecutionCluster != "")
 }
 
+// Serve 以HTTP服务模式运行
+func (arg *Arguments) Serve() bool {
+	return arg.Listen != ""
+}
+
+// LocalInput 是否从本地文件读取任务输入，此时即使同时设置了在线扫描参数也不会从制品分析服务拉取任务
+func (arg *Arguments) LocalInput() bool {
+	return arg.Offline() || arg.ScanFile() || arg.Batch()
+}
+
 // ShouldKeepRunning 是否无限循环拉取任务执行
 func (arg *Arguments) ShouldKeepRunning() bool {
-	return arg.Online() && arg.KeepRunning && arg.TaskId == ""
+	return arg.Online() && !arg.LocalInput() && arg.KeepRunning && arg.TaskId == ""
+}
+
+// WorkerCount 同时执行子任务的worker数量
+func (arg *Arguments) WorkerCount() int {
+	if !arg.ShouldKeepRunning() || arg.Parallel < 1 {
+		return 1
+	}
+	return arg.Parallel
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "******"
+}
+
+func redactUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.User == nil {
+		return rawUrl
+	}
+	return u.Redacted()
 }
@@ -0,0 +1,25 @@
+package object
+
+import "testing"
+
+func TestShouldKeepRunning(t *testing.T) {
+	online := func() *Arguments {
+		return &Arguments{Url: "http://bkrepo", Token: "token", ExecutionCluster: "default", KeepRunning: true, Parallel: 4}
+	}
+	if args := online(); !args.ShouldKeepRunning() || args.WorkerCount() != 4 {
+		t.Fatal("expect pull mode keep running")
+	}
+
+	// 通过BKREPO_前缀的环境变量设置了在线扫描参数时，本地输入仍只执行一次
+	scanFile := online()
+	scanFile.FilePath = "app.tar"
+	offline := online()
+	offline.InputFilePath, offline.OutputFilePath = "input.json", "output.json"
+	batch := online()
+	batch.BatchInput = "/data"
+	for name, args := range map[string]*Arguments{"file": scanFile, "offline": offline, "batch": batch} {
+		if args.ShouldKeepRunning() || args.WorkerCount() != 1 {
+			t.Errorf("expect %s mode not keep running", name)
+		}
+	}
+}
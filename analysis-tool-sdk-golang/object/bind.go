@@ -0,0 +1,209 @@
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind 将工具配置解析到v指向的结构体中，所有字段的解析与校验错误会合并后返回
+//
+// 字段通过标签声明对应的参数，未声明arg标签的字段会被忽略：
+//
+//	arg:"key[,required]"  参数键，指定required时参数不存在或值为空会返回错误
+//	default:"value"       参数不存在时使用的默认值
+//	enum:"a,b,c"          参数的可选值
+//	min:"0" max:"100"     数值或时长类型参数的取值范围，时长类型例如min:"1m"
+//
+// 支持string、bool、整数、浮点数、time.Duration、[]string与map[string]string类型的字段，其他类型的字段按json解析
+// 参数类型与字段类型不匹配或值无法解析时返回错误
+func (toolConfig *ToolConfig) Bind(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("bind target must be a non-nil pointer to struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs []error
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup("arg")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		if key == "" {
+			key = field.Name
+		}
+		if err := toolConfig.bindField(rv.Field(i), field, key, opts == "required"); err != nil {
+			errs = append(errs, fmt.Errorf("arg %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (toolConfig *ToolConfig) bindField(fv reflect.Value, field reflect.StructField, key string, required bool) error {
+	arg, found := toolConfig.findArg(key)
+	if !found || arg.Value == "" {
+		if required {
+			return errors.New("required")
+		}
+		defaultValue, ok := field.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		arg = &Argument{Key: key, Value: defaultValue}
+	}
+
+	if err := checkArgType(arg.Type, fv.Type()); err != nil {
+		return err
+	}
+	if enum, ok := field.Tag.Lookup("enum"); ok && !slices.Contains(strings.Split(enum, ","), arg.Value) {
+		return fmt.Errorf("value %s not in [%s]", arg.Value, enum)
+	}
+	if err := setValue(fv, arg.Type, arg.Value); err != nil {
+		return err
+	}
+	return checkRange(fv, field.Tag)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// expectedArgType 获取字段对应的参数类型，string类型字段不限制参数类型
+func expectedArgType(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return ArgTypeDuration
+	case t.Kind() == reflect.String:
+		return ""
+	case t.Kind() == reflect.Bool:
+		return ArgTypeBoolean
+	case isNumber(t.Kind()):
+		return ArgTypeNumber
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		return ArgTypeStringList
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String:
+		return ArgTypeMap
+	default:
+		return ArgTypeJson
+	}
+}
+
+// checkArgType 检查参数类型与字段类型是否匹配，STRING类型参数可以解析到任意类型的字段，未指定类型时不检查
+// DURATION类型字段兼容单位为毫秒的NUMBER类型参数
+func checkArgType(argType string, t reflect.Type) error {
+	expected := expectedArgType(t)
+	if argType == "" || argType == ArgTypeString || expected == "" || argType == expected ||
+		expected == ArgTypeDuration && argType == ArgTypeNumber {
+		return nil
+	}
+	return fmt.Errorf("type %s mismatch field type %s", argType, t)
+}
+
+func setValue(fv reflect.Value, argType string, value string) error {
+	switch expectedArgType(fv.Type()) {
+	case ArgTypeDuration:
+		d, err := parseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %s", value)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case ArgTypeStringList:
+		list, err := parseStringList(argType, value)
+		if err != nil {
+			return fmt.Errorf("invalid string list %s", value)
+		}
+		fv.Set(reflect.ValueOf(list).Convert(fv.Type()))
+		return nil
+	case ArgTypeMap:
+		m, err := parseMap(argType, value)
+		if err != nil {
+			return fmt.Errorf("invalid map %s", value)
+		}
+		fv.Set(reflect.ValueOf(m).Convert(fv.Type()))
+		return nil
+	case ArgTypeJson:
+		if err := json.Unmarshal([]byte(value), fv.Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid json: %w", err)
+		}
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool %s", value)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %s", value)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %s", value)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %s", value)
+		}
+		fv.SetFloat(f)
+	}
+	return nil
+}
+
+// checkRange 检查数值与时长类型字段是否在min与max标签指定的范围内
+func checkRange(fv reflect.Value, tag reflect.StructTag) error {
+	parse := func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+	var value float64
+	switch {
+	case fv.Type() == durationType:
+		value = float64(fv.Int())
+		parse = func(s string) (float64, error) {
+			d, err := parseDuration(s)
+			return float64(d), err
+		}
+	case fv.CanInt():
+		value = float64(fv.Int())
+	case fv.CanUint():
+		value = float64(fv.Uint())
+	case fv.CanFloat():
+		value = fv.Float()
+	default:
+		return nil
+	}
+	if minValue, ok := tag.Lookup("min"); ok {
+		if m, err := parse(minValue); err == nil && value < m {
+			return fmt.Errorf("value %s less than min %s", formatValue(fv), minValue)
+		}
+	}
+	if maxValue, ok := tag.Lookup("max"); ok {
+		if m, err := parse(maxValue); err == nil && value > m {
+			return fmt.Errorf("value %s greater than max %s", formatValue(fv), maxValue)
+		}
+	}
+	return nil
+}
+
+func formatValue(fv reflect.Value) string {
+	return fmt.Sprint(fv.Interface())
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64 && kind != reflect.Uintptr
+}
@@ -0,0 +1,99 @@
+package object
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindConfig struct {
+	MaxTime     int64   `arg:"maxTime,required"`
+	PackageType string  `arg:"packageType" enum:"GENERIC,MAVEN"`
+	ScanLicense bool    `arg:"scanLicense" default:"true"`
+	Workers     int     `arg:"workers" default:"4" min:"1" max:"16"`
+	Ratio       float64 `arg:"ratio"`
+	Ignored     string
+}
+
+func TestBind(t *testing.T) {
+	config := &ToolConfig{Args: []Argument{
+		{Type: ArgTypeNumber, Key: "maxTime", Value: "60000"},
+		{Type: ArgTypeString, Key: "packageType", Value: "MAVEN"},
+		{Type: ArgTypeString, Key: "ratio", Value: "0.5"},
+	}}
+	c := &bindConfig{Ignored: "ignored"}
+	if err := config.Bind(c); err != nil {
+		t.Fatal(err.Error())
+	}
+	if c.MaxTime != 60000 || c.PackageType != "MAVEN" || !c.ScanLicense || c.Workers != 4 || c.Ratio != 0.5 ||
+		c.Ignored != "ignored" {
+		t.Fatalf("unexpected config: %+v", c)
+	}
+}
+
+type richBindConfig struct {
+	Excludes []string          `arg:"excludes"`
+	Headers  map[string]string `arg:"headers"`
+	Timeout  time.Duration     `arg:"timeout" default:"5m" max:"1h"`
+	MaxTime  time.Duration     `arg:"maxTime"`
+	Rules    []struct {
+		Name string `json:"name"`
+	} `arg:"rules"`
+}
+
+func TestBindRichTypes(t *testing.T) {
+	config := &ToolConfig{Args: []Argument{
+		{Type: ArgTypeStringList, Key: "excludes", Value: `["*.md","docs/"]`},
+		{Type: ArgTypeString, Key: "headers", Value: "X-Token:abc"},
+		{Type: ArgTypeNumber, Key: "maxTime", Value: "60000"},
+		{Type: ArgTypeJson, Key: "rules", Value: `[{"name":"r1"}]`},
+	}}
+	c := new(richBindConfig)
+	if err := config.Bind(c); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(c.Excludes) != 2 || c.Headers["X-Token"] != "abc" || c.Timeout != 5*time.Minute ||
+		c.MaxTime != time.Minute || len(c.Rules) != 1 || c.Rules[0].Name != "r1" {
+		t.Fatalf("unexpected config: %+v", c)
+	}
+
+	config.Args = []Argument{
+		{Type: ArgTypeBoolean, Key: "excludes", Value: "true"},
+		{Type: ArgTypeDuration, Key: "timeout", Value: "2h"},
+		{Type: ArgTypeJson, Key: "rules", Value: `{`},
+	}
+	err := config.Bind(new(richBindConfig))
+	for _, msg := range []string{
+		"arg excludes: type BOOLEAN mismatch field type []string",
+		"arg timeout: value 2h0m0s greater than max 1h",
+		"arg rules: invalid json",
+	} {
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("expect %q in %v", msg, err)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	config := &ToolConfig{Args: []Argument{
+		{Type: ArgTypeString, Key: "packageType", Value: "NPM"},
+		{Type: ArgTypeNumber, Key: "scanLicense", Value: "1"},
+		{Type: ArgTypeNumber, Key: "workers", Value: "32"},
+		{Type: ArgTypeNumber, Key: "ratio", Value: "abc"},
+	}}
+	err := config.Bind(new(bindConfig))
+	if err == nil {
+		t.Fatal("expect error")
+	}
+	for _, msg := range []string{
+		"arg maxTime: required",
+		"arg packageType: value NPM not in [GENERIC,MAVEN]",
+		"arg scanLicense: type NUMBER mismatch field type bool",
+		"arg workers: value 32 greater than max 16",
+		"arg ratio: invalid number abc",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expect %q in %q", msg, err.Error())
+		}
+	}
+}
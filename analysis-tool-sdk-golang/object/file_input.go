@@ -0,0 +1,96 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileTaskId 直接扫描文件时使用的任务id
+const fileTaskId = "local"
+
+// ToolArgFlags 通过命令行指定的工具参数，格式为[TYPE:]key=value
+type ToolArgFlags []Argument
+
+// String 实现flag.Value
+func (f *ToolArgFlags) String() string {
+	kvs := make([]string, len(*f))
+	for i, arg := range *f {
+		kvs[i] = arg.Type + ":" + arg.Key + "=" + arg.Value
+	}
+	return strings.Join(kvs, ",")
+}
+
+// Set 实现flag.Value
+func (f *ToolArgFlags) Set(value string) error {
+	arg, err := ParseToolArg(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, *arg)
+	return nil
+}
+
+// SetKeyValue 设置单个工具参数，用于从配置文件读取，key未指定TYPE时根据配置文件中值的类型确定参数类型
+// 非字符串的值按json格式保存，例如MAP与JSON类型参数的值可以直接写为对象
+func (f *ToolArgFlags) SetKeyValue(key string, value any) error {
+	if !strings.Contains(key, ":") {
+		switch value.(type) {
+		case bool:
+			key = ArgTypeBoolean + ":" + key
+		case int, int64, uint64, float64:
+			key = ArgTypeNumber + ":" + key
+		case []any:
+			key = ArgTypeStringList + ":" + key
+		case map[string]any:
+			key = ArgTypeJson + ":" + key
+		}
+	}
+	v, err := configValue(value)
+	if err != nil {
+		return fmt.Errorf("invalid value of arg %s: %w", key, err)
+	}
+	return f.Set(key + "=" + v)
+}
+
+// ParseToolArg 解析[TYPE:]key=value格式的工具参数，未指定TYPE时为STRING
+func ParseToolArg(value string) (*Argument, error) {
+	kv, v, ok := strings.Cut(value, "=")
+	if !ok || kv == "" {
+		return nil, errors.New("invalid arg " + value + ", expect [TYPE:]key=value")
+	}
+	argType, key, typed := strings.Cut(kv, ":")
+	if !typed {
+		key = kv
+		argType = ArgTypeString
+	}
+	switch argType {
+	case ArgTypeString, ArgTypeNumber, ArgTypeBoolean,
+		ArgTypeStringList, ArgTypeMap, ArgTypeJson, ArgTypeDuration:
+	default:
+		return nil, errors.New("unsupported arg type " + argType)
+	}
+	return &Argument{Type: argType, Key: key, Value: v}, nil
+}
+
+// NewFileToolInput 根据--file等参数生成工具输入
+func (arg *Arguments) NewFileToolInput() (*ToolInput, error) {
+	filePath, err := filepath.Abs(arg.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	args := []Argument{
+		{Type: ArgTypeString, Key: "packageType", Value: arg.PackageType},
+		{Type: ArgTypeNumber, Key: "maxTime", Value: strconv.FormatInt(arg.MaxTime.Milliseconds(), 10)},
+	}
+	// 命令行指定的参数优先
+	args = append(append([]Argument{}, arg.ToolArgs...), args...)
+	return &ToolInput{
+		TaskId:     fileTaskId + "-" + strconv.FormatInt(time.Now().UnixMilli(), 10),
+		ToolConfig: ToolConfig{Args: args},
+		FilePath:   filePath,
+	}, nil
+}
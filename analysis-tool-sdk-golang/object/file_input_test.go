@@ -0,0 +1,58 @@
+package object
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseToolArg(t *testing.T) {
+	cases := map[string]Argument{
+		"BOOLEAN:scanLicense=true":  {Type: ArgTypeBoolean, Key: "scanLicense", Value: "true"},
+		"NUMBER:downloaderWorker=4": {Type: ArgTypeNumber, Key: "downloaderWorker", Value: "4"},
+		// 未指定TYPE时不根据value推断类型
+		"scanLicense=true":         {Type: ArgTypeString, Key: "scanLicense", Value: "true"},
+		"version=1.0":              {Type: ArgTypeString, Key: "version", Value: "1.0"},
+		"name=nan":                 {Type: ArgTypeString, Key: "name", Value: "nan"},
+		"name=inf":                 {Type: ArgTypeString, Key: "name", Value: "inf"},
+		"regex=.*\\.so":            {Type: ArgTypeString, Key: "regex", Value: ".*\\.so"},
+		"STRING:regex=123":         {Type: ArgTypeString, Key: "regex", Value: "123"},
+		"downloaderHeaders=a:b,c:": {Type: ArgTypeString, Key: "downloaderHeaders", Value: "a:b,c:"},
+	}
+	for value, expected := range cases {
+		arg, err := ParseToolArg(value)
+		if err != nil {
+			t.Fatalf("parse %s failed: %s", value, err.Error())
+		}
+		if *arg != expected {
+			t.Fatalf("parse %s, expect %+v, got %+v", value, expected, *arg)
+		}
+	}
+	for _, value := range []string{"novalue", "=value", "LIST:key=value"} {
+		if _, err := ParseToolArg(value); err == nil {
+			t.Fatalf("expect parse %s failed", value)
+		}
+	}
+}
+
+func TestNewFileToolInput(t *testing.T) {
+	args := &Arguments{FilePath: "app.tar", PackageType: "DOCKER", MaxTime: 10 * time.Minute}
+	_ = args.ToolArgs.Set("NUMBER:maxTime=1000")
+	input, err := args.NewFileToolInput()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if input.TaskId == "" || input.FilePath == "app.tar" {
+		t.Fatalf("unexpected input: %+v", input)
+	}
+	if input.ToolConfig.GetStringArg("packageType") != "DOCKER" || input.MaxTime() != time.Second {
+		t.Fatalf("unexpected tool config: %+v", input.ToolConfig)
+	}
+
+	// 未指定类型的参数为STRING，同样可以通过GetIntArg获取
+	args.ToolArgs = nil
+	_ = args.ToolArgs.Set("maxTime=2000")
+	input, err = args.NewFileToolInput()
+	if err != nil || input.MaxTime() != 2*time.Second {
+		t.Fatalf("unexpected untyped maxTime: %+v, %v", input, err)
+	}
+}
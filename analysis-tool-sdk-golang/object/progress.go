@@ -0,0 +1,11 @@
+package object
+
+// Progress 任务执行进度，通过心跳请求上报到制品分析服务
+type Progress struct {
+	// Stage 当前执行阶段，例如downloading、scanning
+	Stage string `json:"stage"`
+	// Percent 当前阶段的完成百分比，取值范围[0,100]
+	Percent float64 `json:"percent"`
+	// Counters 当前阶段的计数，例如已下载字节数、已扫描文件数
+	Counters map[string]int64 `json:"counters,omitempty"`
+}
@@ -0,0 +1,100 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// ReporterDefault 根据运行模式选择，离线模式写入输出文件，--file模式未指定输出文件时输出到标准输出，其他情况上报到制品分析服务
+	ReporterDefault = "default"
+	// ReporterFile 写入--output指定的文件
+	ReporterFile = "file"
+	// ReporterAnalyst 上报到制品分析服务
+	ReporterAnalyst = "analyst"
+	// ReporterStdout 输出到标准输出
+	ReporterStdout = "stdout"
+	// ReporterWebhook POST到--webhook-url指定的地址
+	ReporterWebhook = "webhook"
+)
+
+var reporterNames = []string{ReporterDefault, ReporterFile, ReporterAnalyst, ReporterStdout, ReporterWebhook}
+
+// ReporterFlags 通过命令行指定的结果上报方式，多个上报方式使用逗号分隔
+type ReporterFlags []string
+
+// String 实现flag.Value
+func (f *ReporterFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set 实现flag.Value
+func (f *ReporterFlags) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(reporterNames, name) {
+			return errors.New("unsupported reporter " + name + ", expect one of " + strings.Join(reporterNames, ","))
+		}
+		if !slices.Contains(*f, name) {
+			*f = append(*f, name)
+		}
+	}
+	return nil
+}
+
+// HeaderFlags 通过命令行指定的请求头，格式为k1:v1,k2:v2
+type HeaderFlags map[string]string
+
+// String 实现flag.Value
+func (f *HeaderFlags) String() string {
+	keys := make([]string, 0, len(*f))
+	for k := range *f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// Set 实现flag.Value
+func (f *HeaderFlags) Set(value string) error {
+	headers, err := ParseHeaders(value)
+	if err != nil {
+		return err
+	}
+	if *f == nil {
+		*f = make(HeaderFlags)
+	}
+	for k, v := range headers {
+		(*f)[k] = v
+	}
+	return nil
+}
+
+// SetKeyValue 设置单个请求头，用于从配置文件读取
+func (f *HeaderFlags) SetKeyValue(key string, value any) error {
+	if *f == nil {
+		*f = make(HeaderFlags)
+	}
+	(*f)[key] = fmt.Sprint(value)
+	return nil
+}
+
+// ParseHeaders 解析k1:v1,k2:v2格式的请求头
+func ParseHeaders(headersStr string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if len(headersStr) == 0 {
+		return headers, nil
+	}
+	kvs := strings.Split(headersStr, ",")
+	for i := range kvs {
+		h := strings.Split(kvs[i], ":")
+		if len(h) != 2 {
+			return nil, errors.New("headers error: " + kvs[i])
+		}
+		headers[strings.TrimSpace(h[0])] = strings.TrimSpace(h[1])
+	}
+	return headers, nil
+}
@@ -0,0 +1,147 @@
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envPrefix 参数对应环境变量的前缀，例如--task-id对应BKREPO_TASK_ID
+const envPrefix = "BKREPO_"
+
+// keyValueSetter 可以从配置文件中的键值对设置的参数
+type keyValueSetter interface {
+	SetKeyValue(key string, value any) error
+}
+
+// loadSources 从环境变量与配置文件中读取命令行未指定的参数，优先级为命令行 > 环境变量 > 配置文件 > 默认值
+func loadSources(fs *flag.FlagSet, args *Arguments) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if err := loadEnv(fs, set); err != nil {
+		return err
+	}
+	if args.ConfigFile != "" {
+		if err := loadConfigFile(fs, set, args.ConfigFile); err != nil {
+			return fmt.Errorf("load config file %s failed: %w", args.ConfigFile, err)
+		}
+	}
+	if args.Token == "" && args.TokenFile != "" {
+		token, err := os.ReadFile(args.TokenFile)
+		if err != nil {
+			return fmt.Errorf("read token file failed: %w", err)
+		}
+		args.Token = strings.TrimSpace(string(token))
+	}
+	return nil
+}
+
+// EnvName 获取参数对应的环境变量名
+func EnvName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func loadEnv(fs *flag.FlagSet, set map[string]bool) error {
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(EnvName(f.Name))
+		if set[f.Name] || !ok {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid env %s: %w", EnvName(f.Name), err))
+			return
+		}
+		set[f.Name] = true
+	})
+	return errors.Join(errs...)
+}
+
+// loadConfigFile 读取yaml或json格式的配置文件，键为参数名，可以重复指定的参数值为列表，--arg与--webhook-headers的值也可以为对象
+func loadConfigFile(fs *flag.FlagSet, set map[string]bool, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// json是yaml的子集，统一按yaml解析
+	config := make(map[string]any)
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil {
+			errs = append(errs, errors.New("unknown arg "+name))
+			continue
+		}
+		if set[name] {
+			continue
+		}
+		if err := setFlag(f, config[name]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid arg %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setFlag(f *flag.Flag, value any) error {
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return err
+			}
+			if err := f.Value.Set(s); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		setter, ok := f.Value.(keyValueSetter)
+		if !ok {
+			return errors.New("object value not supported")
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := setter.SetKeyValue(k, v[k]); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		s, err := configValue(v)
+		if err != nil {
+			return err
+		}
+		return f.Value.Set(s)
+	}
+	return nil
+}
+
+// configValue 将配置文件中的值转换为参数值，非字符串的值按json格式转换，例如对象、列表与大数字
+func configValue(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,106 @@
+package object
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSources(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("secret-token\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	config := `
+url: http://config.example.com
+execution-cluster: config
+parallel: 4
+max-time: 10m
+token-file: ` + tokenFile + `
+arg:
+  scanLicense: true
+webhook-headers:
+  X-Token: "a:b"
+`
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Setenv("BKREPO_EXECUTION_CLUSTER", "env")
+	t.Setenv("BKREPO_PARALLEL", "2")
+
+	args := new(Arguments)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, args)
+	if err := fs.Parse([]string{"--config", configFile, "--parallel", "8"}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := loadSources(fs, args); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 命令行 > 环境变量 > 配置文件
+	if args.Parallel != 8 || args.ExecutionCluster != "env" || args.Url != "http://config.example.com" {
+		t.Fatalf("unexpected precedence: %+v", args)
+	}
+	if args.Token != "secret-token" || args.MaxTime != 10*time.Minute || args.WebhookHeaders["X-Token"] != "a:b" {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+	if len(args.ToolArgs) != 1 || args.ToolArgs[0].Type != ArgTypeBoolean || args.ToolArgs[0].Key != "scanLicense" {
+		t.Fatalf("unexpected tool args: %+v", args.ToolArgs)
+	}
+
+	if err := os.WriteFile(configFile, []byte("unknown: 1"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	args = new(Arguments)
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, args)
+	_ = fs.Parse([]string{"--config", configFile})
+	if err := loadSources(fs, args); err == nil {
+		t.Fatal("expect unknown arg error")
+	}
+}
+
+func TestLoadSourcesObjectToolArgs(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	config := `
+arg:
+  MAP:labels:
+    team: security
+  excludes: [vendor, node_modules]
+  rules:
+    level: 2
+  threshold: 1000000
+`
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	args := new(Arguments)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, args)
+	if err := fs.Parse([]string{"--config", configFile}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := loadSources(fs, args); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	toolConfig := &ToolConfig{Args: args.ToolArgs}
+	if m, err := toolConfig.GetMapArg("labels"); err != nil || m["team"] != "security" {
+		t.Fatalf("unexpected labels: %v, %v", m, err)
+	}
+	if list, err := toolConfig.GetStringListArg("excludes"); err != nil || len(list) != 2 || list[1] != "node_modules" {
+		t.Fatalf("unexpected excludes: %v, %v", list, err)
+	}
+	rules := struct{ Level int }{}
+	if err := toolConfig.GetJsonArg("rules", &rules); err != nil || rules.Level != 2 {
+		t.Fatalf("unexpected rules: %v, %v", rules, err)
+	}
+	if n, err := toolConfig.GetIntArg("threshold"); err != nil || n != 1000000 {
+		t.Fatalf("unexpected threshold: %v, %v", n, err)
+	}
+}
@@ -0,0 +1,18 @@
+package object
+
+// ToolTypeStandard 标准扫描工具类型
+const ToolTypeStandard = "standard"
+
+// ToolInfo 扫描工具配置，对应tool.json
+type ToolInfo struct {
+	Name                string     `json:"name"`
+	Image               string     `json:"image"`
+	Cmd                 string     `json:"cmd"`
+	Version             string     `json:"version"`
+	Args                []Argument `json:"args"`
+	Type                string     `json:"type"`
+	Description         string     `json:"description"`
+	SupportFileNameExt  []string   `json:"supportFileNameExt"`
+	SupportPackageTypes []string   `json:"supportPackageTypes"`
+	SupportScanTypes    []string   `json:"supportScanTypes"`
+}
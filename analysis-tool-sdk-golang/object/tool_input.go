@@ -1,10 +1,29 @@
 package object
 
 import (
+	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	// ArgTypeString 字符串类型参数
+	ArgTypeString = "STRING"
+	// ArgTypeNumber 数字类型参数
+	ArgTypeNumber = "NUMBER"
+	// ArgTypeBoolean 布尔类型参数
+	ArgTypeBoolean = "BOOLEAN"
+	// ArgTypeStringList 字符串列表类型参数，值为json数组，例如["a","b"]
+	ArgTypeStringList = "STRING_LIST"
+	// ArgTypeMap 键值对类型参数，值为json对象，例如{"k1":"v1"}
+	ArgTypeMap = "MAP"
+	// ArgTypeJson 任意json类型参数
+	ArgTypeJson = "JSON"
+	// ArgTypeDuration 时长类型参数，值为time.ParseDuration支持的格式，例如10m，纯数字时单位为毫秒
+	ArgTypeDuration = "DURATION"
+)
+
 // ToolInput 工具输入
 type ToolInput struct {
 	TaskId     string     `json:"taskId"`
@@ -35,39 +54,21 @@ type FileUrl struct {
 	Size   int64  `json:"size"`
 }
 
-// GetBoolArg 获取布尔类型参数
+// GetBoolArg 获取布尔类型参数，兼容值可以解析为布尔值的STRING类型参数
 func (toolConfig *ToolConfig) GetBoolArg(key string) (bool, error) {
-	var argument Argument
-	for _, arg := range toolConfig.Args {
-		if arg.Key == key && arg.Type == "BOOLEAN" {
-			argument = arg
-			break
-		}
-	}
+	argument := toolConfig.findTypedArg(key, ArgTypeBoolean)
 	return strconv.ParseBool(argument.Value)
 }
 
-// GetFloatArg 获取浮点类型参数
+// GetFloatArg 获取浮点类型参数，兼容值可以解析为数字的STRING类型参数
 func (toolConfig *ToolConfig) GetFloatArg(key string) (float64, error) {
-	var argument Argument
-	for _, arg := range toolConfig.Args {
-		if arg.Key == key && arg.Type == "NUMBER" {
-			argument = arg
-			break
-		}
-	}
+	argument := toolConfig.findTypedArg(key, ArgTypeNumber)
 	return strconv.ParseFloat(argument.Value, 64)
 }
 
-// GetIntArg 获取整形参数
+// GetIntArg 获取整形参数，兼容值可以解析为整数的STRING类型参数
 func (toolConfig *ToolConfig) GetIntArg(key string) (int64, error) {
-	var argument Argument
-	for _, arg := range toolConfig.Args {
-		if arg.Key == key && arg.Type == "NUMBER" {
-			argument = arg
-			break
-		}
-	}
+	argument := toolConfig.findTypedArg(key, ArgTypeNumber)
 	return strconv.ParseInt(argument.Value, 10, 64)
 }
 
@@ -75,7 +76,7 @@ func (toolConfig *ToolConfig) GetIntArg(key string) (int64, error) {
 func (toolConfig *ToolConfig) GetStringArg(key string) string {
 	var argument Argument
 	for _, arg := range toolConfig.Args {
-		if arg.Key == key && arg.Type == "STRING" {
+		if arg.Key == key && arg.Type == ArgTypeString {
 			argument = arg
 			break
 		}
@@ -83,6 +84,95 @@ func (toolConfig *ToolConfig) GetStringArg(key string) string {
 	return argument.Value
 }
 
+// GetStringListArg 获取字符串列表类型参数
+// 兼容STRING类型参数，值为json数组时按json解析，否则按逗号分隔
+func (toolConfig *ToolConfig) GetStringListArg(key string) ([]string, error) {
+	argument, _ := toolConfig.findArg(key)
+	return parseStringList(argument.Type, argument.Value)
+}
+
+// GetMapArg 获取键值对类型参数
+// 兼容STRING类型参数，值为json对象时按json解析，否则按k1:v1,k2:v2格式解析
+func (toolConfig *ToolConfig) GetMapArg(key string) (map[string]string, error) {
+	argument, _ := toolConfig.findArg(key)
+	return parseMap(argument.Type, argument.Value)
+}
+
+// GetJsonArg 将json类型参数解析到v中，兼容STRING类型参数，参数不存在时不修改v
+func (toolConfig *ToolConfig) GetJsonArg(key string, v any) error {
+	argument, _ := toolConfig.findArg(key)
+	if argument.Value == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(argument.Value), v)
+}
+
+// GetDurationArg 获取时长类型参数，兼容NUMBER类型参数，此时单位为毫秒
+func (toolConfig *ToolConfig) GetDurationArg(key string) (time.Duration, error) {
+	argument, _ := toolConfig.findArg(key)
+	return parseDuration(argument.Value)
+}
+
+// findArg 查找参数，不区分参数类型，参数不存在时返回空参数
+func (toolConfig *ToolConfig) findArg(key string) (*Argument, bool) {
+	for i := range toolConfig.Args {
+		if toolConfig.Args[i].Key == key {
+			return &toolConfig.Args[i], true
+		}
+	}
+	return &Argument{Key: key}, false
+}
+
+// findTypedArg 查找指定类型或STRING类型的参数，例如命令行未指定类型的--arg，参数不存在时返回空参数
+func (toolConfig *ToolConfig) findTypedArg(key string, argType string) *Argument {
+	for i := range toolConfig.Args {
+		arg := &toolConfig.Args[i]
+		if arg.Key == key && (arg.Type == argType || arg.Type == ArgTypeString) {
+			return arg
+		}
+	}
+	return &Argument{Key: key}
+}
+
+func parseStringList(argType string, value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if argType == ArgTypeStringList || strings.HasPrefix(strings.TrimSpace(value), "[") {
+		var list []string
+		if err := json.Unmarshal([]byte(value), &list); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	list := strings.Split(value, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list, nil
+}
+
+func parseMap(argType string, value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if argType == ArgTypeMap || strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var m map[string]string
+		if err := json.Unmarshal([]byte(value), &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return ParseHeaders(value)
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	return time.ParseDuration(value)
+}
+
 // FileUrlMap 获取文件sh256到url的映射
 func (toolInput *ToolInput) FileUrlMap() map[string]FileUrl {
 	fileUrlMap := make(map[string]FileUrl, len(toolInput.FileUrls))
@@ -0,0 +1,67 @@
+package object
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypedArgs(t *testing.T) {
+	config := &ToolConfig{Args: []Argument{
+		{Type: ArgTypeStringList, Key: "list", Value: `["a","b"]`},
+		{Type: ArgTypeString, Key: "legacyList", Value: "a, b"},
+		{Type: ArgTypeMap, Key: "map", Value: `{"k1":"v1"}`},
+		{Type: ArgTypeString, Key: "legacyMap", Value: "k1:v1, k2:v2"},
+		{Type: ArgTypeJson, Key: "json", Value: `{"level":2}`},
+		{Type: ArgTypeDuration, Key: "duration", Value: "10m"},
+		{Type: ArgTypeNumber, Key: "legacyDuration", Value: "1500"},
+	}}
+
+	for _, key := range []string{"list", "legacyList"} {
+		list, err := config.GetStringListArg(key)
+		if err != nil || len(list) != 2 || list[0] != "a" || list[1] != "b" {
+			t.Fatalf("unexpected %s: %v, %v", key, list, err)
+		}
+	}
+	m, err := config.GetMapArg("map")
+	if err != nil || m["k1"] != "v1" {
+		t.Fatalf("unexpected map: %v, %v", m, err)
+	}
+	m, err = config.GetMapArg("legacyMap")
+	if err != nil || m["k1"] != "v1" || m["k2"] != "v2" {
+		t.Fatalf("unexpected legacy map: %v, %v", m, err)
+	}
+	v := struct{ Level int }{}
+	if err := config.GetJsonArg("json", &v); err != nil || v.Level != 2 {
+		t.Fatalf("unexpected json: %v, %v", v, err)
+	}
+	if d, err := config.GetDurationArg("duration"); err != nil || d != 10*time.Minute {
+		t.Fatalf("unexpected duration: %v, %v", d, err)
+	}
+	if d, err := config.GetDurationArg("legacyDuration"); err != nil || d != 1500*time.Millisecond {
+		t.Fatalf("unexpected legacy duration: %v, %v", d, err)
+	}
+	if m, err := config.GetMapArg("notExists"); err != nil || m != nil {
+		t.Fatalf("unexpected not exists map: %v, %v", m, err)
+	}
+}
+
+func TestStringScalarArgs(t *testing.T) {
+	config := &ToolConfig{Args: []Argument{
+		{Type: ArgTypeString, Key: "workers", Value: "4"},
+		{Type: ArgTypeString, Key: "ratio", Value: "0.5"},
+		{Type: ArgTypeString, Key: "enabled", Value: "true"},
+		{Type: ArgTypeString, Key: "name", Value: "abc"},
+	}}
+	if n, err := config.GetIntArg("workers"); err != nil || n != 4 {
+		t.Fatalf("unexpected workers: %v, %v", n, err)
+	}
+	if f, err := config.GetFloatArg("ratio"); err != nil || f != 0.5 {
+		t.Fatalf("unexpected ratio: %v, %v", f, err)
+	}
+	if b, err := config.GetBoolArg("enabled"); err != nil || !b {
+		t.Fatalf("unexpected enabled: %v, %v", b, err)
+	}
+	if _, err := config.GetIntArg("name"); err == nil {
+		t.Fatal("expect error when value is not a number")
+	}
+}
@@ -22,6 +22,22 @@ type ToolOutput struct {
 	Err    string     `json:"err"`
 	TaskId string     `json:"taskId"`
 	Result *Result    `json:"result"`
+	// Log 任务执行日志，指定--task-log时由框架设置
+	Log *TaskLog `json:"log,omitempty"`
+}
+
+// TaskLog 任务执行日志
+type TaskLog struct {
+	// Name 日志文件名
+	Name string `json:"name"`
+	// Size 日志原始大小
+	Size int64 `json:"size"`
+	// Truncated 附加到结果中的日志是否只包含末尾部分
+	Truncated bool `json:"truncated"`
+	// Content gzip压缩后base64编码的日志内容，--task-log=attach时设置
+	Content string `json:"content,omitempty"`
+	// Url 日志上传地址，--task-log=upload时设置
+	Url string `json:"url,omitempty"`
 }
 
 // Result 工具扫描结果
@@ -79,6 +95,15 @@ func NewFailedOutput(err error) *ToolOutput {
 	}
 }
 
+// NewTimeoutOutput 创建超时输出，result为超时前已扫描出的部分结果，可以为nil
+func NewTimeoutOutput(err error, result *Result) *ToolOutput {
+	return &ToolOutput{
+		Status: StatusTimeout,
+		Err:    err.Error(),
+		Result: result,
+	}
+}
+
 // NewOutput 创建工具标准输出
 func NewOutput(status TaskStatus, result *Result) *ToolOutput {
 	return &ToolOutput{
@@ -32,6 +32,11 @@ func NewChunkDownloader(workerCount int, tmpDir string, headers map[string]strin
 
 // Download 分片下载
 func (d *ChunkDownloader) Download(url string) (io.ReadCloser, error) {
+	return d.downloadCounting(url, nil)
+}
+
+// downloadCounting 分片下载，并通过counter统计已下载的字节数
+func (d *ChunkDownloader) downloadCounting(url string, counter *byteCounter) (io.ReadCloser, error) {
 	defer timer("chunk download finished,")()
 	Info("downloading %s", url)
 	file, err := os.CreateTemp(d.TmpDir, "*-download.tmp")
@@ -39,14 +44,16 @@ func (d *ChunkDownloader) Download(url string) (io.ReadCloser, error) {
 		return nil, err
 	}
 
-	if err = d.chunkDownload(url, file); err != nil {
+	start := time.Now()
+	if err = d.chunkDownload(url, file, counter); err != nil {
 		return nil, err
 	}
+	MetricDownloadDuration.ObserveSince(start, downloaderChunk)
 
 	return file, nil
 }
 
-func (d *ChunkDownloader) chunkDownload(url string, outputFile *os.File) error {
+func (d *ChunkDownloader) chunkDownload(url string, outputFile *os.File, counter *byteCounter) error {
 	fileSize, err := d.getFileSize(url)
 	if err != nil {
 		return err
@@ -70,7 +77,7 @@ func (d *ChunkDownloader) chunkDownload(url string, outputFile *os.File) error {
 
 		g.Go(
 			func() error {
-				return d.doDownload(ctx, url, outputFile, start, end)
+				return d.doDownload(ctx, url, outputFile, start, end, counter)
 			},
 		)
 	}
@@ -78,7 +85,14 @@ func (d *ChunkDownloader) chunkDownload(url string, outputFile *os.File) error {
 	return g.Wait()
 }
 
-func (d *ChunkDownloader) doDownload(ctx context.Context, url string, file *os.File, start int, end int) error {
+func (d *ChunkDownloader) doDownload(
+	ctx context.Context,
+	url string,
+	file *os.File,
+	start int,
+	end int,
+	counter *byteCounter,
+) error {
 	defer timer(fmt.Sprintf("download chunk %d-%d success,", start, end))()
 	Info("start download chunk %d-%d", start, end)
 	req, err := retryablehttp.NewRequest("GET", url, nil)
@@ -118,6 +132,8 @@ func (d *ChunkDownloader) doDownload(ctx context.Context, url string, file *os.F
 			if err != nil {
 				return err
 			}
+			counter.add(int64(n))
+			MetricDownloadBytes.Add(float64(n), downloaderChunk)
 			off += n
 		}
 
@@ -5,51 +5,76 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
+// outputWaitDelay 子进程退出后等待输出读取完成的最长时间
+// 子进程创建的后台进程可能继承并一直持有输出管道，超时后不再等待
+const outputWaitDelay = 3 * time.Second
+
 // ExecAndLog 执行命令并实时输出日志
+// 命令的标准输出与标准错误通过ctx中保存的日志输出，并带有stream=stdout|stderr属性
 func ExecAndLog(ctx context.Context, name string, args []string, workDir string) error {
 	cmd := exec.CommandContext(ctx, name, args...)
+	logger := LoggerFromContext(ctx)
 
 	if len(workDir) > 0 {
 		cmd.Dir = workDir
-		Info("work directory: %s", workDir)
+		InfoContext(ctx, "work directory: %s", workDir)
 	}
 
-	Info("will execute: %s", cmd.String())
+	InfoContext(ctx, "will execute: %s", cmd.String())
 
-	outScanner, errScanner, err := createScanner(cmd)
-	if err != nil {
-		return err
-	}
+	outReader, errReader, closeOutput := createOutputPipe(cmd)
+	outScanner, errScanner := newLineScanner(outReader), newLineScanner(errReader)
+	cmd.WaitDelay = outputWaitDelay
 
 	if err := cmd.Start(); err != nil {
+		closeOutput()
 		return err
 	}
 
 	// 用于出错时上报最后[keepLine]行日志
 	const keepLine = 10
 	logs := [keepLine]string{}
+	var wg sync.WaitGroup
+	wg.Add(2)
 	go func() {
+		defer wg.Done()
 		i := 0
 		var line string
 		for errScanner.Scan() {
 			line = errScanner.Text()
-			Info(line + "\n")
+			logger.InfoContext(ctx, line, "stream", "stderr")
 			logs[i%keepLine] = fmt.Sprintf("%d    : %s", i, line)
 			i++
 		}
+		// 单行过长导致Scan提前结束时丢弃剩余输出，避免子进程写输出时阻塞
+		_, _ = io.Copy(io.Discard, errReader)
 	}()
 
 	go func() {
+		defer wg.Done()
 		for outScanner.Scan() {
-			Info(outScanner.Text() + "\n")
+			logger.InfoContext(ctx, outScanner.Text(), "stream", "stdout")
 		}
+		_, _ = io.Copy(io.Discard, outReader)
 	}()
 
-	if err := cmd.Wait(); err != nil {
+	// 子进程退出后最多等待outputWaitDelay，不会因为后台进程持有输出管道而一直阻塞
+	err := cmd.Wait()
+	// cmd.Wait返回后不会再有输出写入，关闭管道使读取输出的goroutine结束
+	closeOutput()
+	wg.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		WarnContext(ctx, "command exited but output pipes still held by other processes, stop reading output")
+		err = nil
+	}
+	if err != nil {
 		errMsg := make([]string, 0, keepLine)
 		for i := range logs {
 			l := logs[i]
@@ -63,20 +88,22 @@ func ExecAndLog(ctx context.Context, name string, args []string, workDir string)
 	return nil
 }
 
-func createScanner(cmd *exec.Cmd) (*bufio.Scanner, *bufio.Scanner, error) {
-	outPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, err
-	}
-	errPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, nil, err
-	}
+func createOutputPipe(cmd *exec.Cmd) (outReader *io.PipeReader, errReader *io.PipeReader, closeOutput func()) {
+	outReader, outWriter := io.Pipe()
+	errReader, errWriter := io.Pipe()
+	// 使用非*os.File的Writer，cmd.Wait会在WaitDelay超时后放弃复制输出
+	cmd.Stdout = outWriter
+	cmd.Stderr = errWriter
 
-	outScanner := bufio.NewScanner(outPipe)
-	outScanner.Split(bufio.ScanLines)
-	errScanner := bufio.NewScanner(errPipe)
-	errScanner.Split(bufio.ScanLines)
+	closeOutput = func() {
+		_ = outWriter.Close()
+		_ = errWriter.Close()
+	}
+	return outReader, errReader, closeOutput
+}
 
-	return outScanner, errScanner, nil
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	return scanner
 }
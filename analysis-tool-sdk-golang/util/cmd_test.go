@@ -27,3 +27,25 @@ func TestExecAndLog(t *testing.T) {
 	}
 	cancel()
 }
+
+func TestExecAndLogTimeoutWithOrphanOutput(t *testing.T) {
+	// 后台进程在sh被杀死后仍持有stderr并持续输出
+	script := "(while true; do echo line >&2; done) & wait"
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := ExecAndLog(ctx, "sh", []string{"-c", script}, ""); err == nil {
+		t.Fatal("expect error when ctx timeout")
+	}
+}
+
+func TestExecAndLogOrphanHoldOutput(t *testing.T) {
+	// sh正常退出，后台进程继续持有输出管道，ctx没有超时时间
+	script := "sleep 30 & echo done"
+	start := time.Now()
+	if err := ExecAndLog(context.Background(), "sh", []string{"-c", script}, ""); err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > outputWaitDelay+5*time.Second {
+		t.Fatalf("exec returned after %s", elapsed)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"time"
 )
 
 // Downloader 下载器接口
@@ -23,6 +24,7 @@ func NewDownloader() Downloader {
 // Download 从指定url获取输入流
 func (d *DefaultDownloader) Download(url string) (io.ReadCloser, error) {
 	Info("downloading %s", url)
+	start := time.Now()
 	response, err := DefaultClient.Get(url)
 	if err != nil {
 		return nil, err
@@ -32,5 +34,34 @@ func (d *DefaultDownloader) Download(url string) (io.ReadCloser, error) {
 		return nil, errors.New("download failed, status: " + response.Status)
 	}
 
-	return response.Body, nil
+	return &metricsReader{ReadCloser: response.Body, downloader: downloaderDefault, start: start}, nil
+}
+
+const (
+	// downloaderDefault 默认下载器的指标标签
+	downloaderDefault = "default"
+	// downloaderChunk 分片下载器的指标标签
+	downloaderChunk = "chunk"
+)
+
+// metricsReader 读取时记录下载字节数，关闭时记录下载耗时
+type metricsReader struct {
+	io.ReadCloser
+	downloader string
+	start      time.Time
+	closed     bool
+}
+
+func (r *metricsReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	MetricDownloadBytes.Add(float64(n), r.downloader)
+	return n, err
+}
+
+func (r *metricsReader) Close() error {
+	if !r.closed {
+		r.closed = true
+		MetricDownloadDuration.ObserveSince(r.start, r.downloader)
+	}
+	return r.ReadCloser.Close()
 }
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -28,7 +29,12 @@ const manifestPath = "manifest.json"
 
 // CleanWorkDir 清理工作空间
 func CleanWorkDir() error {
-	return os.RemoveAll(WorkDir)
+	return CleanDir(WorkDir)
+}
+
+// CleanDir 清理指定的工作空间，用于并发执行任务时只清理当前任务所在的目录
+func CleanDir(workDir string) error {
+	return os.RemoveAll(workDir)
 }
 
 // WriteToFile 工具输出写入文件
@@ -49,15 +55,30 @@ func WriteToFile(outputFilePath string, toolOutput *object.ToolOutput) error {
 
 // GenerateInputFile 生成输入文件
 func GenerateInputFile(toolInput *object.ToolInput, downloader Downloader) (*os.File, error) {
+	return GenerateInputFileInDir(toolInput, downloader, WorkDir)
+}
+
+// GenerateInputFileInDir 在指定工作空间中生成输入文件
+func GenerateInputFileInDir(toolInput *object.ToolInput, downloader Downloader, workDir string) (*os.File, error) {
+	return GenerateInputFileContext(context.Background(), toolInput, downloader, workDir)
+}
+
+// GenerateInputFileContext 在指定工作空间中生成输入文件，ctx中保存了进度记录时会上报下载进度
+func GenerateInputFileContext(
+	ctx context.Context,
+	toolInput *object.ToolInput,
+	downloader Downloader,
+	workDir string,
+) (*os.File, error) {
 	if toolInput.FilePath != "" {
 		return os.Open(toolInput.FilePath)
 	}
-	if err := os.MkdirAll(WorkDir, 0766); err != nil {
+	if err := os.MkdirAll(workDir, 0766); err != nil {
 		return nil, err
 	}
 
 	if toolInput.ToolConfig.GetStringArg(ArgKeyPkgType) == PackageTypeDocker {
-		return generateImageTar(toolInput, downloader)
+		return generateImageTar(ctx, toolInput, downloader, workDir)
 	} else {
 		fileUrl := toolInput.FileUrls[0]
 		fileNameRegex := toolInput.ToolConfig.GetStringArg(ArgKeyUnsupportedFileNameRegex)
@@ -65,11 +86,12 @@ func GenerateInputFile(toolInput *object.ToolInput, downloader Downloader) (*os.
 			// 不支持的文件类型直接返回
 			return nil, err
 		}
-		file, err := os.Create(filepath.Join(WorkDir, fileUrl.Name))
+		file, err := os.Create(filepath.Join(workDir, fileUrl.Name))
 		if err != nil {
 			return nil, err
 		}
-		reader, err := downloader.Download(fileUrl.Url)
+		counter := newByteCounter(ctx, ProgressStageDownloading, fileUrl.Size)
+		reader, err := download(downloader, fileUrl.Url, counter)
 		if err != nil {
 			return nil, err
 		}
@@ -163,15 +185,37 @@ func matchUnsupportedFileNameRegex(regex string, fileName string) (bool, error)
 	return regexp.MatchString(regex, fileName)
 }
 
-func generateImageTar(toolInput *object.ToolInput, downloader Downloader) (*os.File, error) {
+// download 下载url，counter不为nil时统计已下载的字节数
+func download(downloader Downloader, url string, counter *byteCounter) (io.ReadCloser, error) {
+	if d, ok := downloader.(interface {
+		downloadCounting(url string, counter *byteCounter) (io.ReadCloser, error)
+	}); ok {
+		// 分片下载器在返回前已下载完成，需要在下载过程中统计
+		return d.downloadCounting(url, counter)
+	}
+	reader, err := downloader.Download(url)
+	if err != nil || counter == nil {
+		return reader, err
+	}
+	return counter.reader(reader), nil
+}
+
+func generateImageTar(
+	ctx context.Context,
+	toolInput *object.ToolInput,
+	downloader Downloader,
+	workDir string,
+) (*os.File, error) {
 	// 获取manifest
 	manifest, err := loadManifest(&toolInput.FileUrls[0], downloader)
 	if err != nil {
 		return nil, err
 	}
+	fileUrlMap := toolInput.FileUrlMap()
+	counter := newByteCounter(ctx, ProgressStageDownloading, imageSize(manifest, fileUrlMap))
 
 	// 构建镜像tar包
-	imageFile, err := os.Create(filepath.Join(WorkDir, "image.tar"))
+	imageFile, err := os.Create(filepath.Join(workDir, "image.tar"))
 	if err != nil {
 		return nil, err
 	}
@@ -179,17 +223,16 @@ func generateImageTar(toolInput *object.ToolInput, downloader Downloader) (*os.F
 	defer tarWriter.Close()
 
 	// 将config写入tar中
-	fileUrlMap := toolInput.FileUrlMap()
 	configFileUrl := fileUrlMap[manifest.Config.Sha256()]
 	configFilePath := manifest.Config.Sha256() + ".json"
 
-	err = loadFromUrlToTar(configFilePath, &configFileUrl, tarWriter, false, "", downloader)
+	err = loadFromUrlToTar(configFilePath, &configFileUrl, tarWriter, false, "", downloader, counter)
 	if err != nil {
 		return nil, err
 	}
 
 	// 将layer写入tar中
-	layers, err := loadLayersToTar(manifest, fileUrlMap, tarWriter, downloader)
+	layers, err := loadLayersToTar(manifest, fileUrlMap, tarWriter, downloader, workDir, counter)
 	if err != nil {
 		return nil, err
 	}
@@ -224,8 +267,10 @@ func loadLayersToTar(
 	fileUrlMap map[string]object.FileUrl,
 	tarWriter *tar.Writer,
 	downloader Downloader,
+	workDir string,
+	counter *byteCounter,
 ) ([]string, error) {
-	cacheDir := filepath.Join(WorkDir, "layer-cache")
+	cacheDir := filepath.Join(workDir, "layer-cache")
 	if err := os.MkdirAll(cacheDir, 0766); err != nil {
 		return nil, err
 	}
@@ -241,7 +286,7 @@ func loadLayersToTar(
 		layerPath := url.Sha256 + "/layer.tar"
 		layers = append(layers, layerPath)
 		dup := layerCount[s] > 1
-		if err := loadFromUrlToTar(layerPath, &url, tarWriter, dup, cacheDir, downloader); err != nil {
+		if err := loadFromUrlToTar(layerPath, &url, tarWriter, dup, cacheDir, downloader, counter); err != nil {
 			return nil, err
 		}
 	}
@@ -260,6 +305,7 @@ func loadFromUrlToTar(
 	dup bool,
 	cacheDir string,
 	downloader Downloader,
+	counter *byteCounter,
 ) error {
 	cached := false
 	cacheFile := filepath.Join(cacheDir, fileUrl.Sha256)
@@ -278,7 +324,7 @@ func loadFromUrlToTar(
 		defer f.Close()
 		src = f
 	} else {
-		layerRes, err := downloader.Download(fileUrl.Url)
+		layerRes, err := download(downloader, fileUrl.Url, counter)
 		if err != nil {
 			return err
 		}
@@ -316,6 +362,15 @@ func loadFromUrlToTar(
 	return nil
 }
 
+// imageSize 计算镜像需要下载的总字节数，重复的layer只会下载一次
+func imageSize(manifest *object.ManifestV2, fileUrlMap map[string]object.FileUrl) int64 {
+	size := fileUrlMap[manifest.Config.Sha256()].Size
+	for s := range manifest.LayerCount() {
+		size += fileUrlMap[s].Size
+	}
+	return size
+}
+
 func writeManifestToTar(configFilePath string, layers []string, tarWriter *tar.Writer) error {
 	manifestV1 := []object.ManifestV1{
 		{
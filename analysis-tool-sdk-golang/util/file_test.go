@@ -11,7 +11,21 @@ import (
 )
 
 func TestGenerateInputFile(t *testing.T) {
-	input := &object.ToolInput{
+	input := dockerToolInput()
+	downloader := &MockDownloader{usedUrl: make(map[string]struct{})}
+	file, err := GenerateInputFileInDir(input, downloader, t.TempDir())
+	if err != nil {
+		t.Fatalf("Generate file failed: %s", err.Error())
+	}
+	defer file.Close()
+	if _, err := os.Stat(file.Name()); err != nil {
+		t.Fatalf("Generated file not exists: %s", file.Name())
+	}
+	os.Remove(file.Name())
+}
+
+func dockerToolInput() *object.ToolInput {
+	return &object.ToolInput{
 		ToolConfig: object.ToolConfig{
 			Args: []object.Argument{
 				{
@@ -42,16 +56,6 @@ func TestGenerateInputFile(t *testing.T) {
 			},
 		},
 	}
-	downloader := &MockDownloader{usedUrl: make(map[string]struct{})}
-	file, err := GenerateInputFile(input, downloader)
-	if err != nil {
-		t.Fatalf("Generate file failed: %s", err.Error())
-	}
-	defer file.Close()
-	if _, err := os.Stat(file.Name()); err != nil {
-		t.Fatalf("Generated file not exists: %s", file.Name())
-	}
-	os.Remove(file.Name())
 }
 
 type MockDownloader struct {
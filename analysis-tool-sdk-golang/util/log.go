@@ -1,21 +1,182 @@
 package util
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"sync/atomic"
 )
 
+const (
+	// LogFormatText 输出key=value格式的日志
+	LogFormatText = "text"
+	// LogFormatJson 输出json格式的日志
+	LogFormatJson = "json"
+)
+
+// logger SDK使用的日志，未调用InitLogger时使用slog默认日志
+var logger atomic.Pointer[slog.Logger]
+
+// captureHandler 同时接收不带ctx的日志的Handler，用于保存完整的任务执行日志
+var captureHandler atomic.Pointer[slog.Handler]
+
+type loggerKey struct{}
+
+// InitLogger 初始化SDK日志并设置为slog默认日志，format取值范围[text,json]，level取值范围[debug,info,warn,error]
+// 日志输出到标准错误，attrs会添加到每条日志中，例如工具名
+func InitLogger(format string, level string, attrs ...any) error {
+	return InitLoggerWithWriter(os.Stderr, format, level, attrs...)
+}
+
+// InitLoggerWithWriter 初始化SDK日志，输出到w，已注册的敏感信息会被隐藏
+func InitLoggerWithWriter(w io.Writer, format string, level string, attrs ...any) error {
+	handler, err := NewHandler(w, format, level)
+	if err != nil {
+		return err
+	}
+	newLogger := slog.New(handler).With(attrs...)
+	logger.Store(newLogger)
+	slog.SetDefault(newLogger)
+	DefaultClient.Logger = newLogger
+	return nil
+}
+
+// NewHandler 创建输出到w的日志Handler，格式与级别参考InitLogger，已注册的敏感信息会被隐藏
+func NewHandler(w io.Writer, format string, level string) (slog.Handler, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %s", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	w = NewRedactWriter(w)
+	switch format {
+	case LogFormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case LogFormatJson:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, errors.New("invalid log format " + format)
+	}
+}
+
+// NewTeeHandler 创建将日志同时输出到多个Handler的Handler
+func NewTeeHandler(handlers ...slog.Handler) slog.Handler {
+	return teeHandler(handlers)
+}
+
+type teeHandler []slog.Handler
+
+// Enabled 任意Handler启用时返回true
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle 输出到所有启用了该级别的Handler
+func (t teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, record.Level) {
+			errs = append(errs, h.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs 为所有Handler添加属性
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+// WithGroup 为所有Handler添加分组
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// Logger 获取SDK使用的日志
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// CaptureLogs 将不带ctx或ctx中未保存日志时输出的日志同时输出到handler，返回的函数用于停止输出
+// 无法区分日志属于哪个任务，只应在同一时间只执行一个任务时使用
+func CaptureLogs(handler slog.Handler) (stop func()) {
+	h := &handler
+	captureHandler.Store(h)
+	return func() { captureHandler.CompareAndSwap(h, nil) }
+}
+
+// packageLogger 不带ctx的日志使用的Logger，调用了CaptureLogs时同时输出到指定的Handler
+func packageLogger() *slog.Logger {
+	l := Logger()
+	if h := captureHandler.Load(); h != nil {
+		return slog.New(NewTeeHandler(l.Handler(), *h))
+	}
+	return l
+}
+
+// WithLogger 将日志保存到ctx中，用于输出带有任务id等属性的日志
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext 获取ctx中保存的日志，不存在时返回Logger()，调用了CaptureLogs时同时输出到指定的Handler
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return packageLogger()
+}
+
+// Debug 输出Debug级别日志
+func Debug(format string, v ...any) {
+	packageLogger().Debug(fmt.Sprintf(format, v...))
+}
+
 // Info 输出Info级别日志
 func Info(format string, v ...any) {
-	slog.Info(fmt.Sprintf(format+"\n", v...))
+	packageLogger().Info(fmt.Sprintf(format, v...))
 }
 
 // Warn 输出Warn级别日志
 func Warn(format string, v ...any) {
-	slog.Warn(fmt.Sprintf(format+"\n", v...))
+	packageLogger().Warn(fmt.Sprintf(format, v...))
 }
 
 // Error 输出Error级别日志
 func Error(format string, v ...any) {
-	slog.Error(fmt.Sprintf(format+"\n", v...))
+	packageLogger().Error(fmt.Sprintf(format, v...))
+}
+
+// InfoContext 使用ctx中保存的日志输出Info级别日志
+func InfoContext(ctx context.Context, format string, v ...any) {
+	LoggerFromContext(ctx).InfoContext(ctx, fmt.Sprintf(format, v...))
+}
+
+// WarnContext 使用ctx中保存的日志输出Warn级别日志
+func WarnContext(ctx context.Context, format string, v ...any) {
+	LoggerFromContext(ctx).WarnContext(ctx, fmt.Sprintf(format, v...))
+}
+
+// ErrorContext 使用ctx中保存的日志输出Error级别日志
+func ErrorContext(ctx context.Context, format string, v ...any) {
+	LoggerFromContext(ctx).ErrorContext(ctx, fmt.Sprintf(format, v...))
 }
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestInitLogger(t *testing.T) {
+	defaultLogger := slog.Default()
+	defaultClientLogger := DefaultClient.Logger
+	defer func() {
+		logger.Store(nil)
+		slog.SetDefault(defaultLogger)
+		DefaultClient.Logger = defaultClientLogger
+	}()
+
+	if err := InitLoggerWithWriter(new(bytes.Buffer), "xml", "info"); err == nil {
+		t.Fatal("expect invalid format error")
+	}
+	buf := new(bytes.Buffer)
+	if err := InitLoggerWithWriter(buf, LogFormatJson, "warn", "tool", "scanner"); err != nil {
+		t.Fatal(err.Error())
+	}
+	Info("ignored")
+	ctx := WithLogger(context.Background(), Logger().With("taskId", "task-1"))
+	if err := ExecAndLog(ctx, "sh", []string{"-c", "echo out; echo 100% err >&2; exit 1"}, ""); err == nil {
+		t.Fatal("expect exec error")
+	}
+	WarnContext(ctx, "exec %s", "finished")
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		record := make(map[string]any)
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid json log %s", line)
+		}
+		records = append(records, record)
+	}
+	// Info级别的日志被过滤
+	if len(records) != 1 || records[0]["msg"] != "exec finished" ||
+		records[0]["tool"] != "scanner" || records[0]["taskId"] != "task-1" {
+		t.Fatalf("unexpected logs: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := InitLoggerWithWriter(buf, LogFormatText, "info"); err != nil {
+		t.Fatal(err.Error())
+	}
+	ctx = WithLogger(context.Background(), Logger())
+	_ = ExecAndLog(ctx, "sh", []string{"-c", "echo 100% err >&2"}, "")
+	if !strings.Contains(buf.String(), `msg="100% err" stream=stderr`) {
+		t.Fatalf("unexpected logs: %s", buf.String())
+	}
+}
+
+func TestCaptureLogs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	handler, err := NewHandler(buf, LogFormatText, "info")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	stop := CaptureLogs(handler)
+	Info("captured")
+	InfoContext(context.Background(), "captured without ctx logger")
+	InfoContext(WithLogger(context.Background(), Logger()), "ctx logger not captured")
+	stop()
+	Info("not captured after stop")
+
+	log := buf.String()
+	if !strings.Contains(log, "msg=captured") || !strings.Contains(log, `msg="captured without ctx logger"`) {
+		t.Fatalf("expect captured logs: %s", log)
+	}
+	if strings.Contains(log, "not captured") {
+		t.Fatalf("unexpected captured logs: %s", log)
+	}
+}
@@ -0,0 +1,342 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DurationBuckets 耗时直方图默认的桶，单位为秒
+var DurationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600}
+
+// DefaultMetrics SDK使用的指标注册表，执行器也可以在其中注册自定义指标
+var DefaultMetrics = NewMetricsRegistry()
+
+var (
+	// MetricTasksPulled 开始执行的任务数量
+	MetricTasksPulled = DefaultMetrics.NewCounter(
+		"bkrepo_analysis_tasks_pulled_total", "Number of tasks pulled or loaded",
+	)
+	// MetricEmptyPulls 未拉取到任务的次数
+	MetricEmptyPulls = DefaultMetrics.NewCounter(
+		"bkrepo_analysis_empty_pulls_total", "Number of pulls that returned no task",
+	)
+	// MetricTasksFinished 执行结束的任务数量，status为任务状态
+	MetricTasksFinished = DefaultMetrics.NewCounter(
+		"bkrepo_analysis_tasks_finished_total", "Number of finished tasks by status", "status",
+	)
+	// MetricDownloadBytes 下载的字节数，downloader为下载器类型
+	MetricDownloadBytes = DefaultMetrics.NewCounter(
+		"bkrepo_analysis_download_bytes_total", "Number of downloaded bytes", "downloader",
+	)
+	// MetricDownloadDuration 下载耗时，downloader为下载器类型
+	MetricDownloadDuration = DefaultMetrics.NewHistogram(
+		"bkrepo_analysis_download_duration_seconds", "Download duration in seconds", DurationBuckets, "downloader",
+	)
+	// MetricExecutorDuration 执行器执行耗时
+	MetricExecutorDuration = DefaultMetrics.NewHistogram(
+		"bkrepo_analysis_executor_duration_seconds", "Executor duration in seconds", DurationBuckets,
+	)
+	// MetricReportDuration 上报结果耗时，result取值范围[success,failed]
+	MetricReportDuration = DefaultMetrics.NewHistogram(
+		"bkrepo_analysis_report_duration_seconds", "Report latency in seconds", DurationBuckets, "result",
+	)
+	// MetricHeartbeatFailures 心跳失败次数
+	MetricHeartbeatFailures = DefaultMetrics.NewCounter(
+		"bkrepo_analysis_heartbeat_failures_total", "Number of failed heartbeats",
+	)
+)
+
+// MetricsRegistry 指标注册表，按Prometheus文本格式输出已注册的指标
+type MetricsRegistry struct {
+	mu          sync.Mutex
+	metrics     []metric
+	constLabels []labelPair
+}
+
+type metric interface {
+	write(w *bufio.Writer, constLabels []labelPair)
+}
+
+type labelPair struct {
+	name  string
+	value string
+}
+
+// NewMetricsRegistry 创建指标注册表
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{}
+}
+
+// SetConstLabels 设置所有指标都带有的标签，例如工具名与执行集群
+func (r *MetricsRegistry) SetConstLabels(labels map[string]string) {
+	pairs := make([]labelPair, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, labelPair{name: name, value: value})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].name < pairs[j].name })
+	r.mu.Lock()
+	r.constLabels = pairs
+	r.mu.Unlock()
+}
+
+// NewCounter 注册计数器，labelNames为标签名，更新时需要按顺序传入相同数量的标签值
+func (r *MetricsRegistry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labelNames: labelNames}, values: make(map[string]*counterValue)}
+	r.register(c)
+	return c
+}
+
+// NewHistogram 注册直方图，buckets为升序的桶上界，labelNames参考NewCounter
+func (r *MetricsRegistry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		buckets: slices.Clone(buckets),
+		values:  make(map[string]*histogramValue),
+	}
+	r.register(h)
+	return h
+}
+
+func (r *MetricsRegistry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo 按Prometheus文本格式输出所有指标
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	constLabels := r.constLabels
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw, constLabels)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler 返回输出指标的HTTP Handler
+func (r *MetricsRegistry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if _, err := r.WriteTo(w); err != nil {
+			Error("write metrics failed: %s", err.Error())
+		}
+	})
+}
+
+// desc 指标描述信息
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+// key 校验标签值数量并生成用于区分序列的键
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (d *desc) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, typ)
+}
+
+// writeSample 输出一条样本，extra为额外的标签，例如直方图的le
+func (d *desc) writeSample(
+	w *bufio.Writer,
+	suffix string,
+	constLabels []labelPair,
+	labelValues []string,
+	extra *labelPair,
+	value float64,
+) {
+	w.WriteString(d.name + suffix)
+	labels := slices.Clone(constLabels)
+	for i, name := range d.labelNames {
+		labels = append(labels, labelPair{name: name, value: labelValues[i]})
+	}
+	if extra != nil {
+		labels = append(labels, *extra)
+	}
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.name + `="` + escapeLabelValue(l.value) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// Counter 计数器
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// Inc 计数加1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加v，v不能为负数
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labelValues: slices.Clone(labelValues)}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+// Value 获取计数，主要用于测试
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cv, ok := c.values[key]; ok {
+		return cv.value
+	}
+	return 0
+}
+
+func (c *Counter) write(w *bufio.Writer, constLabels []labelPair) {
+	c.writeHeader(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		c.writeSample(w, "", constLabels, cv.labelValues, nil, cv.value)
+	}
+}
+
+// Histogram 直方图
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+// ObserveSince 记录从start开始经过的秒数
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count 获取观测次数，主要用于测试
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hv, ok := h.values[key]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer, constLabels []labelPair) {
+	h.writeHeader(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		for i, upper := range h.buckets {
+			le := &labelPair{name: "le", value: formatFloat(upper)}
+			h.writeSample(w, "_bucket", constLabels, hv.labelValues, le, float64(hv.counts[i]))
+		}
+		inf := &labelPair{name: "le", value: "+Inf"}
+		h.writeSample(w, "_bucket", constLabels, hv.labelValues, inf, float64(hv.count))
+		h.writeSample(w, "_sum", constLabels, hv.labelValues, nil, hv.sum)
+		h.writeSample(w, "_count", constLabels, hv.labelValues, nil, float64(hv.count))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	r := NewMetricsRegistry()
+	r.SetConstLabels(map[string]string{"tool": "trivy", "execution_cluster": "default"})
+	counter := r.NewCounter("test_total", "Test counter", "status")
+	histogram := r.NewHistogram("test_seconds", "Test histogram", []float64{1, 10})
+	counter.Inc("SUCCESS")
+	counter.Add(2, `FA"ILED`)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP test_total Test counter
+# TYPE test_total counter
+test_total{execution_cluster="default",tool="trivy",status="FA\"ILED"} 2
+test_total{execution_cluster="default",tool="trivy",status="SUCCESS"} 1
+# HELP test_seconds Test histogram
+# TYPE test_seconds histogram
+test_seconds_bucket{execution_cluster="default",tool="trivy",le="1"} 1
+test_seconds_bucket{execution_cluster="default",tool="trivy",le="10"} 2
+test_seconds_bucket{execution_cluster="default",tool="trivy",le="+Inf"} 2
+test_seconds_sum{execution_cluster="default",tool="trivy"} 5.5
+test_seconds_count{execution_cluster="default",tool="trivy"} 2
+`
+	if sb.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+	if counter.Value("SUCCESS") != 1 || histogram.Count() != 2 {
+		t.Errorf("unexpected values")
+	}
+}
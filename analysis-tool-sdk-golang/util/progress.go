@@ -0,0 +1,138 @@
+package util
+
+import (
+	"context"
+	"github.com/TencentBlueKing/ci-repoAnalysis/analysis-tool-sdk-golang/object"
+	"io"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// ProgressStageDownloading 下载待分析文件阶段
+	ProgressStageDownloading = "downloading"
+	// ProgressStageScanning 执行分析阶段
+	ProgressStageScanning = "scanning"
+
+	// CounterDownloadedBytes 已下载字节数
+	CounterDownloadedBytes = "downloadedBytes"
+	// CounterTotalBytes 需要下载的总字节数
+	CounterTotalBytes = "totalBytes"
+)
+
+type progressKey struct{}
+
+// ProgressTracker 记录任务的最新进度，方法可以并发调用，为nil时忽略所有更新
+type ProgressTracker struct {
+	mu       sync.Mutex
+	progress *object.Progress
+	// logged 最近一次输出日志时的阶段与进度，避免频繁更新时输出过多日志
+	loggedStage   string
+	loggedPercent int
+}
+
+// NewProgressTracker 创建进度记录
+func NewProgressTracker() *ProgressTracker {
+	return &ProgressTracker{}
+}
+
+// Update 更新进度，阶段变化或进度每增加10%时使用ctx中的日志输出当前进度
+func (p *ProgressTracker) Update(ctx context.Context, stage string, percent float64, counters map[string]int64) {
+	if p == nil {
+		return
+	}
+	percent = min(max(percent, 0), 100)
+	p.mu.Lock()
+	p.progress = &object.Progress{Stage: stage, Percent: percent, Counters: maps.Clone(counters)}
+	step := int(percent) / 10
+	shouldLog := stage != p.loggedStage || step > p.loggedPercent
+	if shouldLog {
+		p.loggedStage = stage
+		p.loggedPercent = step
+	}
+	p.mu.Unlock()
+
+	if shouldLog {
+		LoggerFromContext(ctx).InfoContext(
+			ctx, "task progress", "stage", stage, "percent", percent, "counters", counters,
+		)
+	}
+}
+
+// Get 获取最新进度，未更新过进度时返回nil
+func (p *ProgressTracker) Get() *object.Progress {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.progress == nil {
+		return nil
+	}
+	progress := *p.progress
+	progress.Counters = maps.Clone(p.progress.Counters)
+	return &progress
+}
+
+// WithProgress 将进度记录保存到ctx中
+func WithProgress(ctx context.Context, p *ProgressTracker) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFromContext 获取ctx中保存的进度记录，不存在时返回nil
+func ProgressFromContext(ctx context.Context) *ProgressTracker {
+	p, _ := ctx.Value(progressKey{}).(*ProgressTracker)
+	return p
+}
+
+// ReportProgress 上报当前任务的执行进度，进度会在下一次心跳时上报到制品分析服务
+// stage为执行阶段，percent为当前阶段的完成百分比，counters为当前阶段的计数
+func ReportProgress(ctx context.Context, stage string, percent float64, counters map[string]int64) {
+	ProgressFromContext(ctx).Update(ctx, stage, percent, counters)
+}
+
+// byteCounter 统计已下载的字节数并更新下载进度
+type byteCounter struct {
+	ctx        context.Context
+	progress   *ProgressTracker
+	stage      string
+	total      int64
+	downloaded atomic.Int64
+}
+
+func newByteCounter(ctx context.Context, stage string, total int64) *byteCounter {
+	return &byteCounter{ctx: ctx, progress: ProgressFromContext(ctx), stage: stage, total: total}
+}
+
+// add 增加已下载字节数，c为nil时忽略
+func (c *byteCounter) add(n int64) {
+	if c == nil || c.progress == nil || n <= 0 {
+		return
+	}
+	downloaded := c.downloaded.Add(n)
+	var percent float64
+	if c.total > 0 {
+		percent = float64(downloaded) * 100 / float64(c.total)
+	}
+	c.progress.Update(c.ctx, c.stage, percent, map[string]int64{
+		CounterDownloadedBytes: downloaded,
+		CounterTotalBytes:      c.total,
+	})
+}
+
+// reader 返回读取时统计字节数的reader
+func (c *byteCounter) reader(r io.ReadCloser) io.ReadCloser {
+	return &countingReader{ReadCloser: r, counter: c}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	counter *byteCounter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.counter.add(int64(n))
+	return n, err
+}
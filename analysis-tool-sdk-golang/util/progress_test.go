@@ -0,0 +1,34 @@
+package util
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGenerateInputFileProgress(t *testing.T) {
+	progress := NewProgressTracker()
+	ctx := WithProgress(context.Background(), progress)
+	downloader := &MockDownloader{usedUrl: make(map[string]struct{})}
+	file, err := GenerateInputFileContext(ctx, dockerToolInput(), downloader, t.TempDir())
+	if err != nil {
+		t.Fatalf("Generate file failed: %s", err.Error())
+	}
+	_ = file.Close()
+
+	p := progress.Get()
+	if p == nil || p.Stage != ProgressStageDownloading || p.Percent != 100 {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+	// 重复的layer只下载一次
+	if p.Counters[CounterDownloadedBytes] != 18 || p.Counters[CounterTotalBytes] != 18 {
+		t.Errorf("unexpected counters %v", p.Counters)
+	}
+
+	ReportProgress(ctx, ProgressStageScanning, 150, map[string]int64{"files": 3})
+	if p = progress.Get(); p.Stage != ProgressStageScanning || p.Percent != 100 || p.Counters["files"] != 3 {
+		t.Errorf("unexpected progress %+v", p)
+	}
+
+	// 未设置进度记录时忽略
+	ReportProgress(context.Background(), ProgressStageScanning, 50, nil)
+}
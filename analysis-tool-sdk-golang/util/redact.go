@@ -0,0 +1,59 @@
+package util
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// redactedText 敏感信息被替换后的内容
+const redactedText = "******"
+
+var (
+	secretsLock   sync.RWMutex
+	secrets       []string
+	redactLogOnce sync.Once
+)
+
+// AddSecrets 注册需要在日志中隐藏的敏感信息，例如令牌
+// 注册后标准库log与slog默认Logger输出的日志中的敏感信息会被替换为******
+func AddSecrets(values ...string) {
+	secretsLock.Lock()
+	for _, v := range values {
+		if v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	secretsLock.Unlock()
+	redactLogOnce.Do(func() {
+		log.SetOutput(NewRedactWriter(log.Writer()))
+	})
+}
+
+// Redact 将s中已注册的敏感信息替换为******
+func Redact(s string) string {
+	secretsLock.RLock()
+	defer secretsLock.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redactedText)
+	}
+	return s
+}
+
+// NewRedactWriter 创建写入前隐藏已注册敏感信息的Writer，用于输出日志
+func NewRedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+// Write 隐藏敏感信息后写入，返回值为原始内容的长度
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
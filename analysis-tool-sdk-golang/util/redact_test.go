@@ -0,0 +1,18 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedactWriter(t *testing.T) {
+	AddSecrets("secret-token", "")
+	buf := new(bytes.Buffer)
+	w := NewRedactWriter(buf)
+	if _, err := w.Write([]byte("GET /input?token=secret-token failed")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if buf.String() != "GET /input?token=****** failed" {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package util
+
+import "errors"
+
+// ProcessRSS 获取当前进程占用的物理内存，单位为字节，仅支持linux
+func ProcessRSS() (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// FreeDisk 获取path所在文件系统的可用空间，单位为字节，仅支持linux
+func FreeDisk(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}